@@ -0,0 +1,28 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 19:41:26
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 19:41:26
+ * Description: chi适配器
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package chiadapter
+
+import (
+	"path"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/zjguoxin/goslogviewer"
+)
+
+// RegisterChiRoutes 在 Config.BasePath（默认 /log）下注册日志查看器，页面、静态资源、接口及IP限制均由 LogViewer.Handler 提供。
+// chi 的子路由无法获取挂载路径，注册到 r.Route / r.Mount 的子路由时 prefix 为其挂载路径，注册到根路由时为空
+func RegisterChiRoutes(r chi.Router, lv *goslogviewer.LogViewer, prefix string) {
+	base := lv.BasePath()
+	// 页面中的地址及请求路径需要包含挂载路径
+	handler := lv.Handler(path.Join("/", prefix, base))
+	if base != "" {
+		r.Handle(base, handler)
+	}
+	r.Handle(base+"/*", handler)
+}
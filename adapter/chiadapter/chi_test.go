@@ -0,0 +1,78 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 20:05:44
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 20:05:44
+ * Description: chi适配器测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package chiadapter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/zjguoxin/goslogviewer"
+	"github.com/zjguoxin/goslogviewer/internal/adaptertest"
+)
+
+// serve 在 chi 路由上注册日志查看器并启动测试服务
+func serve(t *testing.T, lv *goslogviewer.LogViewer) string {
+	r := chi.NewRouter()
+	RegisterChiRoutes(r, lv, "")
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+func TestRegisterChiRoutes(t *testing.T) {
+	adaptertest.Routes(t, serve)
+}
+
+func TestRegisterChiRoutes_IPRestriction(t *testing.T) {
+	adaptertest.IPRestriction(t, serve)
+}
+
+func TestRegisterChiRoutes_Tail(t *testing.T) {
+	adaptertest.Tail(t, serve)
+}
+
+func TestRegisterChiRoutes_SubRouter(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "app.log"), []byte(`{"level":"INFO","msg":"hello"}`+"\n"), 0644)
+	lv := goslogviewer.New(&goslogviewer.Config{LogDir: tempDir, BasePath: "/logs"})
+	r := chi.NewRouter()
+	r.Route("/admin", func(r chi.Router) {
+		RegisterChiRoutes(r, lv, "/admin")
+	})
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+
+	tests := []struct {
+		path   string
+		status int
+		want   string
+	}{
+		{"/admin/logs", http.StatusOK, `src="/admin/logs/static/jquery-3.5.1.min.js"`},
+		{"/admin/logs/static/dashboard.css", http.StatusOK, ""},
+		{"/admin/logs/getFileContent?name=app.log", http.StatusOK, `"hello"`},
+		{"/logs", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(ts.URL + tt.path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", tt.path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.status || !strings.Contains(string(body), tt.want) {
+			t.Errorf("GET %s: got %d %q", tt.path, resp.StatusCode, body)
+		}
+	}
+}
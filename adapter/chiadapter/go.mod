@@ -0,0 +1,15 @@
+module github.com/zjguoxin/goslogviewer/adapter/chiadapter
+
+go 1.24.0
+
+require (
+	github.com/go-chi/chi/v5 v5.3.2
+	github.com/zjguoxin/goslogviewer v0.0.0-20261017175024-15c2cca18c77
+)
+
+require (
+	github.com/klauspost/compress v1.18.0 // indirect
+	go.etcd.io/bbolt v1.4.3 // indirect
+	golang.org/x/crypto v0.48.0 // indirect
+	golang.org/x/sys v0.41.0 // indirect
+)
@@ -0,0 +1,24 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 19:43:02
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 19:43:02
+ * Description: Echo适配器
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package echoadapter
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/zjguoxin/goslogviewer"
+)
+
+// RegisterEchoRoutes 在 Config.BasePath（默认 /log）下注册日志查看器，页面、静态资源、接口及IP限制均由 LogViewer.Handler 提供
+func RegisterEchoRoutes(e *echo.Echo, lv *goslogviewer.LogViewer) {
+	base := lv.BasePath()
+	handler := echo.WrapHandler(lv.Handler(base))
+	if base != "" {
+		e.Any(base, handler)
+	}
+	e.Any(base+"/*", handler)
+}
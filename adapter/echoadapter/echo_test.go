@@ -0,0 +1,39 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 20:12:09
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 20:12:09
+ * Description: Echo适配器测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package echoadapter
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/zjguoxin/goslogviewer"
+	"github.com/zjguoxin/goslogviewer/internal/adaptertest"
+)
+
+// serve 在 Echo 上注册日志查看器并启动测试服务
+func serve(t *testing.T, lv *goslogviewer.LogViewer) string {
+	e := echo.New()
+	RegisterEchoRoutes(e, lv)
+	ts := httptest.NewServer(e)
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+func TestRegisterEchoRoutes(t *testing.T) {
+	adaptertest.Routes(t, serve)
+}
+
+func TestRegisterEchoRoutes_IPRestriction(t *testing.T) {
+	adaptertest.IPRestriction(t, serve)
+}
+
+func TestRegisterEchoRoutes_Tail(t *testing.T) {
+	adaptertest.Tail(t, serve)
+}
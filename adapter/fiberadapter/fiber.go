@@ -0,0 +1,24 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 19:45:37
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 19:45:37
+ * Description: Fiber适配器
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package fiberadapter
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/zjguoxin/goslogviewer"
+)
+
+// RegisterFiberRoutes 在 Config.BasePath（默认 /log）下注册日志查看器，页面、静态资源、接口及IP限制均由 LogViewer.Handler 提供
+func RegisterFiberRoutes(app *fiber.App, lv *goslogviewer.LogViewer) {
+	base := lv.BasePath()
+	handler := wrapHandler(lv.Handler(base))
+	if base != "" {
+		app.All(base, handler)
+	}
+	app.All(base+"/*", handler)
+}
@@ -0,0 +1,36 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 20:18:37
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 20:18:37
+ * Description: Fiber适配器测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package fiberadapter
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/zjguoxin/goslogviewer"
+	"github.com/zjguoxin/goslogviewer/internal/adaptertest"
+)
+
+// serve 在 Fiber 上注册日志查看器并启动测试服务
+func serve(t *testing.T, lv *goslogviewer.LogViewer) string {
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	RegisterFiberRoutes(app, lv)
+	return listen(t, app)
+}
+
+func TestRegisterFiberRoutes(t *testing.T) {
+	adaptertest.Routes(t, serve)
+}
+
+func TestRegisterFiberRoutes_IPRestriction(t *testing.T) {
+	adaptertest.IPRestriction(t, serve)
+}
+
+func TestRegisterFiberRoutes_Tail(t *testing.T) {
+	adaptertest.Tail(t, serve)
+}
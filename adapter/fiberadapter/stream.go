@@ -0,0 +1,230 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 19:52:18
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 19:52:18
+ * Description: 支持流式响应的 net/http 处理器转换
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package fiberadapter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+)
+
+// probeInterval SSE 空闲时探测连接是否断开的间隔
+const probeInterval = time.Second
+
+// streamThreshold 未调用 Flush 的响应超过该大小后改为流式发送，下载、导出和打包不会整个缓存在内存中
+const streamThreshold = 64 << 10
+
+// wrapHandler 将 net/http 处理器转换为 Fiber 处理器。
+// adaptor.HTTPHandler 会缓存整个响应，TailHandler 的 SSE 无法推送，
+// 因此处理器首次 Flush 或响应超过 streamThreshold 后改用 fasthttp 的 BodyStreamWriter 边写边发。
+// fasthttp 不通知连接断开，SSE 空闲时每隔 probeInterval 在事件之间写入一行注释探测连接，
+// 写入失败或服务器关闭时取消请求的 context
+func wrapHandler(h http.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req, err := adaptor.ConvertRequest(c, true)
+		if err != nil {
+			return err
+		}
+		// RequestCtx 在处理器返回后会被复用，不能作为流式响应的父 context，其 Done 只在服务器关闭时关闭
+		ctx, cancel := context.WithCancel(context.Background())
+		shutdown := c.Context().Done()
+		go func() {
+			select {
+			case <-shutdown:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		req = req.WithContext(ctx)
+
+		w := newStreamWriter()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			defer w.close()
+			h.ServeHTTP(w, req)
+		}()
+
+		select {
+		case <-done:
+			// 处理器未调用 Flush 且响应较小，按普通响应返回
+			cancel()
+			w.writeHeaderTo(c)
+			if c.Method() != fiber.MethodHead {
+				c.Response().SetBody(w.buf.Bytes())
+			}
+			return nil
+		case <-w.started:
+		}
+
+		w.writeHeaderTo(c)
+		if c.Method() == fiber.MethodHead {
+			// HEAD 只返回响应头，流式响应体不会结束，直接结束处理器
+			cancel()
+			w.pr.CloseWithError(io.ErrClosedPipe)
+			c.Response().SkipBody = true
+			return nil
+		}
+		probe := strings.HasPrefix(w.header.Get("Content-Type"), "text/event-stream")
+		c.Context().SetBodyStreamWriter(func(bw *bufio.Writer) {
+			defer cancel()
+			defer w.pr.CloseWithError(io.ErrClosedPipe)
+
+			chunks := make(chan []byte)
+			go w.readChunks(ctx, chunks)
+			ticker := time.NewTicker(probeInterval)
+			defer ticker.Stop()
+
+			// boundary 已发送的内容是否以完整的事件结尾，只有在事件之间才能插入注释
+			boundary := true
+			send := func(p []byte) bool {
+				bw.Write(p)
+				if err := bw.Flush(); err != nil {
+					return false // 客户端已断开
+				}
+				boundary = bytes.HasSuffix(p, []byte("\n\n"))
+				return true
+			}
+			if w.buf.Len() > 0 && !send(w.buf.Bytes()) {
+				return
+			}
+			for {
+				select {
+				case p, ok := <-chunks:
+					if !ok || !send(p) {
+						return
+					}
+					ticker.Reset(probeInterval)
+				case <-ticker.C:
+					if probe && boundary && !send([]byte(":\n\n")) {
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		})
+		return nil
+	}
+}
+
+// streamWriter 首次 Flush 或缓存超过 streamThreshold 前缓存响应，之后通过管道交给 BodyStreamWriter
+type streamWriter struct {
+	header  http.Header
+	status  int
+	buf     bytes.Buffer
+	started chan struct{}
+	pr      *io.PipeReader
+	pw      *io.PipeWriter
+
+	mu        sync.Mutex
+	streaming bool
+}
+
+func newStreamWriter() *streamWriter {
+	pr, pw := io.Pipe()
+	return &streamWriter{
+		header:  make(http.Header),
+		started: make(chan struct{}),
+		pr:      pr,
+		pw:      pw,
+	}
+}
+
+func (w *streamWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *streamWriter) WriteHeader(status int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	w.mu.Lock()
+	streaming := w.streaming
+	if !streaming {
+		defer w.mu.Unlock()
+		n, err := w.buf.Write(p)
+		if w.buf.Len() > streamThreshold {
+			w.startLocked()
+		}
+		return n, err
+	}
+	w.mu.Unlock()
+	return w.pw.Write(p)
+}
+
+// Flush 首次调用时开始流式发送，之后每次 Write 都会立即发给客户端
+func (w *streamWriter) Flush() {
+	w.WriteHeader(http.StatusOK)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.startLocked()
+}
+
+// startLocked 开始流式发送，调用方需持有 mu
+func (w *streamWriter) startLocked() {
+	if !w.streaming {
+		w.streaming = true
+		close(w.started)
+	}
+}
+
+// readChunks 读取处理器写入管道的内容，管道关闭或 ctx 取消后结束
+func (w *streamWriter) readChunks(ctx context.Context, chunks chan<- []byte) {
+	defer close(chunks)
+	for {
+		chunk := make([]byte, 32*1024)
+		n, err := w.pr.Read(chunk)
+		if n > 0 {
+			select {
+			case chunks <- chunk[:n]:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// close 处理器返回后结束管道
+func (w *streamWriter) close() {
+	w.pw.Close()
+}
+
+// writeHeaderTo 将状态码和响应头写入 Fiber 响应
+func (w *streamWriter) writeHeaderTo(c *fiber.Ctx) {
+	w.mu.Lock()
+	status := w.status
+	w.mu.Unlock()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	c.Status(status)
+	for key, values := range w.header {
+		for _, value := range values {
+			c.Response().Header.Add(key, value)
+		}
+	}
+}
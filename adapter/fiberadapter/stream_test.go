@@ -0,0 +1,123 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/18 10:48:21
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/18 10:48:21
+ * Description: 流式响应转换测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package fiberadapter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// listen 在随机端口启动 Fiber，app.Test 会等待完整响应，流式响应需要真实的监听
+func listen(t *testing.T, app *fiber.App) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	go app.Listener(ln)
+	t.Cleanup(func() { app.ShutdownWithTimeout(time.Second) })
+	return "http://" + ln.Addr().String()
+}
+
+func TestWrapHandler_Disconnect(t *testing.T) {
+	canceled := make(chan struct{})
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/events", wrapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": connected\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		close(canceled)
+	})))
+	url := listen(t, app)
+
+	resp, err := http.Get(url + "/events")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Read(make([]byte, 64))
+	resp.Body.Close()
+
+	// 客户端断开后处理器的 context 应在几次探测内取消，而不是等待处理器下一次写入
+	select {
+	case <-canceled:
+	case <-time.After(10 * probeInterval):
+		t.Fatal("Expected request context to be canceled after the client disconnected")
+	}
+}
+
+func TestWrapHandler_Head(t *testing.T) {
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.All("/events", wrapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, ": connected\n\n")
+		w.(http.Flusher).Flush()
+		for r.Context().Err() == nil {
+			fmt.Fprint(w, "data: x\n\n")
+			w.(http.Flusher).Flush()
+			time.Sleep(10 * time.Millisecond)
+		}
+	})))
+	url := listen(t, app)
+
+	// HEAD 只返回响应头，同一连接上的下一个请求不能读到 HEAD 的响应体
+	client := &http.Client{Transport: &http.Transport{MaxConnsPerHost: 1}, Timeout: 5 * time.Second}
+	for _, method := range []string{"HEAD", "HEAD", "GET"} {
+		req, _ := http.NewRequest(method, url+"/events", nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%s failed: %v", method, err)
+		}
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+			t.Errorf("%s: unexpected response %d %v", method, resp.StatusCode, resp.Header)
+		}
+		if method == "GET" {
+			buf := make([]byte, len(": connected\n\n"))
+			if _, err := io.ReadFull(resp.Body, buf); err != nil || string(buf) != ": connected\n\n" {
+				t.Errorf("Unexpected body: %q %v", buf, err)
+			}
+		}
+		resp.Body.Close()
+	}
+}
+
+func TestWrapHandler_LargeResponse(t *testing.T) {
+	release := make(chan struct{})
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/download", wrapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bytes.Repeat([]byte("x"), streamThreshold+1))
+		<-release
+		w.Write([]byte("end"))
+	})))
+	url := listen(t, app)
+
+	// 未调用 Flush 的大响应在处理器返回前就开始发送，不会整个缓存在内存中
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(url + "/download")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	head := make([]byte, streamThreshold+1)
+	if _, err := io.ReadFull(resp.Body, head); err != nil {
+		t.Fatalf("Expected body before the handler returned: %v", err)
+	}
+	close(release)
+	if rest, err := io.ReadAll(resp.Body); err != nil || string(rest) != "end" {
+		t.Errorf("Unexpected rest of body: %q %v", rest, err)
+	}
+}
@@ -9,42 +9,28 @@
 package adapter
 
 import (
-	"html/template"
-	"net/http"
+	"path"
 
 	"github.com/gin-gonic/gin"
 	"github.com/zjguoxin/goslogviewer"
-	"github.com/zjguoxin/goslogviewer/middleware"
 )
 
-func RegisterGinRoutes(r *gin.Engine, lv *goslogviewer.LogViewer) {
-	r.Static("/static", "./adapter/templates/source")
+// RegisterGinRoutes 在 Config.BasePath（默认 /log）下注册日志查看器，r 可以是 *gin.Engine 或 *gin.RouterGroup，
+// 页面、内嵌静态资源、接口及IP限制均由 LogViewer.Handler 提供
+func RegisterGinRoutes(r gin.IRouter, lv *goslogviewer.LogViewer) {
+	base := lv.BasePath()
 
-	// 1. 从嵌入的文件系统加载模板
-	tmpl := template.Must(
-		template.New("").
-			ParseFS(templateFS, "templates/log.html"),
-	)
-
-	r.SetHTMLTemplate(tmpl)
-
-	group := r.Group("/log", middleware.IPRestriction(
-		lv.GetConfig().EnableIPRestriction,
-		lv.GetConfig().AllowedIPs,
-		lv.GetConfig().TrustedProxies,
-	))
-	{
-		group.GET("", func(c *gin.Context) {
-			c.HTML(http.StatusOK, "log.html", gin.H{
-				"head":  "日志查看器",
-				"title": "日志查看器",
-			})
-		})
-		group.GET("/getLogFilesList", func(c *gin.Context) { lv.GetFilesHandler(c.Writer, c.Request) })
-		group.GET("/getFileContent", func(c *gin.Context) { lv.GetContentHandler(c.Writer, c.Request) })
-		group.POST("/clearFileContent", func(c *gin.Context) { lv.ClearFileContentHandler(c.Writer, c.Request) })
-		group.POST("/deleteAllFiles", func(c *gin.Context) { lv.DeleteAllFilesHandler(c.Writer, c.Request) })
-		group.GET("/exportFile", func(c *gin.Context) { lv.ExportFileHandler(c.Writer, c.Request) })
+	// 页面中的地址需要包含路由组的前缀
+	prefix := base
+	if group, ok := r.(interface{ BasePath() string }); ok {
+		prefix = path.Join("/", group.BasePath(), base)
+	}
+	handler := gin.WrapH(lv.Handler(prefix))
 
+	if base == "" {
+		r.Any("/*path", handler)
+		return
 	}
+	r.Any(base, handler)
+	r.Any(base+"/*path", handler)
 }
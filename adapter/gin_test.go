@@ -0,0 +1,73 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 20:46:53
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 20:46:53
+ * Description: Gin适配器测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package adapter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zjguoxin/goslogviewer"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func newViewer(t *testing.T, basePath string) *goslogviewer.LogViewer {
+	t.Helper()
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "app.log"), []byte(`{"level":"INFO","msg":"hello"}`+"\n"), 0644)
+	return goslogviewer.New(&goslogviewer.Config{LogDir: tempDir, BasePath: basePath})
+}
+
+// get 发送请求并检查状态码及响应内容
+func get(t *testing.T, r http.Handler, path string, status int, want string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	if w.Code != status || !strings.Contains(w.Body.String(), want) {
+		t.Errorf("GET %s: got %d, want %d containing %q", path, w.Code, status, want)
+	}
+}
+
+func TestRegisterGinRoutes(t *testing.T) {
+	r := gin.New()
+	// 调用方自己的 /static 路由不受影响
+	r.GET("/static/app.css", func(c *gin.Context) { c.String(http.StatusOK, "own") })
+	RegisterGinRoutes(r, newViewer(t, ""))
+
+	get(t, r, "/log", http.StatusOK, `href="/log/static/bootstrap.min.css"`)
+	get(t, r, "/log/static/jquery-3.5.1.min.js", http.StatusOK, "jQuery")
+	get(t, r, "/log/getFileContent?name=app.log", http.StatusOK, `"hello"`)
+	get(t, r, "/static/app.css", http.StatusOK, "own")
+}
+
+func TestRegisterGinRoutes_BasePath(t *testing.T) {
+	r := gin.New()
+	RegisterGinRoutes(r, newViewer(t, "/admin/logs/"))
+
+	get(t, r, "/admin/logs", http.StatusOK, `const base = "/admin/logs"`)
+	get(t, r, "/admin/logs/static/dashboard.css", http.StatusOK, "")
+	get(t, r, "/admin/logs/getLogFilesList", http.StatusOK, `"app.log"`)
+	get(t, r, "/log", http.StatusNotFound, "")
+}
+
+func TestRegisterGinRoutes_RouterGroup(t *testing.T) {
+	r := gin.New()
+	RegisterGinRoutes(r.Group("/admin"), newViewer(t, "/logs"))
+
+	get(t, r, "/admin/logs", http.StatusOK, `src="/admin/logs/static/jquery-3.5.1.min.js"`)
+	get(t, r, "/admin/logs/static/dashboard.css", http.StatusOK, "")
+	get(t, r, "/admin/logs/getFileContent?name=app.log", http.StatusOK, `"hello"`)
+}
@@ -1,14 +0,0 @@
-/**
- * @Author: guxline zjguoxin@163.com
- * @Date: 2025/7/4 07:16:42
- * @LastEditors: guxline zjguoxin@163.com
- * @LastEditTime: 2025/7/4 07:16:42
- * Description:
- * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
- */
-package adapter
-
-import "embed"
-
-//go:embed templates/log.html
-var templateFS embed.FS
@@ -0,0 +1,312 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 22:48:16
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 22:48:16
+ * Description: 审计日志，记录清空、删除、导出及被拒绝的访问
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// 审计结果
+const (
+	AuditSuccess = "success" // 操作成功
+	AuditDenied  = "denied"  // 认证失败、IP 不在白名单或没有操作权限
+	AuditFailed  = "failed"  // 操作执行失败
+)
+
+// ActionAccess 访问被拒绝时的审计动作，其余动作与操作权限同名，如 PermClear
+const ActionAccess = "access"
+
+const (
+	auditRecentSize  = 1000      // 内存中保留的最近审计事件数
+	defaultAuditSize = 100       // 审计接口默认返回的事件数
+	maxAuditLine     = 1 << 20   // 审计文件中超过该长度的行被忽略
+	auditReadChunk   = 64 * 1024 // 从审计文件末尾向前读取的块大小
+)
+
+// AuditEvent 审计事件
+type AuditEvent struct {
+	Time    time.Time `json:"time"`
+	IP      string    `json:"ip"`             // 客户端IP，规则与IP限制相同
+	User    string    `json:"user,omitempty"` // 已认证的用户，认证失败时为尝试登录的用户名
+	Action  string    `json:"action"`
+	Source  string    `json:"source,omitempty"`
+	File    string    `json:"file,omitempty"`
+	Path    string    `json:"path,omitempty"` // 请求路径
+	Outcome string    `json:"outcome"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// Audit 记录审计事件：写入 Config.AuditLogger 及 Config.AuditFile，并在内存中保留最近的事件
+func (lv *LogViewer) Audit(event AuditEvent) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	lv.auditMu.Lock()
+	defer lv.auditMu.Unlock()
+
+	lv.auditEvents = append(lv.auditEvents, event)
+	if len(lv.auditEvents) > auditRecentSize {
+		lv.auditEvents = append(lv.auditEvents[:0], lv.auditEvents[len(lv.auditEvents)-auditRecentSize:]...)
+	}
+
+	if logger := lv.config.AuditLogger; logger != nil {
+		level := slog.LevelInfo
+		if event.Outcome != AuditSuccess {
+			level = slog.LevelWarn
+		}
+		logger.LogAttrs(context.Background(), level, "audit",
+			slog.Time("time", event.Time),
+			slog.String("ip", event.IP),
+			slog.String("user", event.User),
+			slog.String("action", event.Action),
+			slog.String("source", event.Source),
+			slog.String("file", event.File),
+			slog.String("path", event.Path),
+			slog.String("outcome", event.Outcome),
+			slog.String("error", event.Error),
+		)
+	}
+
+	if lv.config.AuditFile == "" {
+		return nil
+	}
+	if lv.auditFile == nil {
+		if err := os.MkdirAll(filepath.Dir(lv.config.AuditFile), 0755); err != nil {
+			return err
+		}
+		// 只追加写入，不截断已有记录
+		f, err := os.OpenFile(lv.config.AuditFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return err
+		}
+		lv.auditFile = f
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = lv.auditFile.Write(append(line, '\n'))
+	return err
+}
+
+// AuditEvents 返回最近的 limit 条审计事件，最新的在前。配置了 AuditFile 时从文件读取，否则返回内存中的事件
+func (lv *LogViewer) AuditEvents(limit int) ([]AuditEvent, error) {
+	if limit <= 0 {
+		limit = defaultAuditSize
+	}
+
+	var events []AuditEvent
+	if lv.config.AuditFile == "" {
+		lv.auditMu.Lock()
+		events = append(events, lv.auditEvents...)
+		lv.auditMu.Unlock()
+	} else {
+		var err error
+		if events, err = readAuditFile(lv.config.AuditFile, limit); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(events) > limit {
+		events = events[len(events)-limit:]
+	}
+	result := make([]AuditEvent, 0, len(events))
+	for i := len(events) - 1; i >= 0; i-- {
+		result = append(result, events[i])
+	}
+	return result, nil
+}
+
+// readAuditFile 从文件末尾向前读取最后 limit 条事件（按时间先后排列），只读取所需的部分，文件不存在时返回空列表
+func readAuditFile(path string, limit int) ([]AuditEvent, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		events []AuditEvent // 最新的在前
+		line   []byte       // 当前行已读取的后半部分
+		skip   bool         // 当前行过长，丢弃到上一个换行符
+	)
+	add := func(b []byte) {
+		var event AuditEvent
+		if len(bytes.TrimSpace(b)) > 0 && json.Unmarshal(b, &event) == nil {
+			events = append(events, event)
+		}
+	}
+	buf := make([]byte, auditReadChunk)
+	pos := info.Size()
+	for pos > 0 && len(events) < limit {
+		n := min(int64(len(buf)), pos)
+		pos -= n
+		chunk := buf[:n]
+		if _, err := f.ReadAt(chunk, pos); err != nil {
+			return nil, err
+		}
+		for len(events) < limit {
+			i := bytes.LastIndexByte(chunk, '\n')
+			if i < 0 {
+				if !skip && len(line)+len(chunk) > maxAuditLine {
+					skip, line = true, nil
+				} else if !skip {
+					line = append(append([]byte(nil), chunk...), line...)
+				}
+				break
+			}
+			if !skip && len(line)+len(chunk)-i-1 <= maxAuditLine {
+				add(append(append([]byte(nil), chunk[i+1:]...), line...))
+			}
+			line, skip = nil, false
+			chunk = chunk[:i]
+		}
+	}
+	if pos == 0 && !skip && len(events) < limit {
+		add(line) // 文件的第一行
+	}
+
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	return events, nil
+}
+
+// isAuditFile 判断路径是否为审计文件，审计文件位于日志目录中时不能被查看、清空或删除。
+// 比较前解析符号链接，指向审计文件的链接及经由链接目录的路径同样视为审计文件，审计文件的路径在 New 中解析
+func (lv *LogViewer) isAuditFile(path string) bool {
+	if lv.auditPath == "" {
+		return false
+	}
+	path, err := realPath(path)
+	return err == nil && path == lv.auditPath
+}
+
+// realPath 返回解析符号链接后的绝对路径，文件不存在时解析已存在的上级目录
+func realPath(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved, nil
+	}
+	if dir := filepath.Dir(path); dir != path {
+		if resolved, err := realPath(dir); err == nil {
+			return filepath.Join(resolved, filepath.Base(path)), nil
+		}
+	}
+	return path, nil
+}
+
+// authorizeAudit 检查用户能否查看审计日志，配置 Roles 时需要 PermAudit 权限，不受角色的日志源及文件范围限制；
+// 未配置 Roles 时需要通过认证，未配置认证时只在 DevMode 下允许
+func (lv *LogViewer) authorizeAudit(identity *Identity) error {
+	if len(lv.config.Roles) == 0 {
+		if identity != nil || lv.config.DevMode {
+			return nil
+		}
+		return fmt.Errorf("%w: anonymous cannot view audit log", ErrForbidden)
+	}
+	for _, role := range lv.roles(identity) {
+		if contains(role.Permissions, PermAudit) || contains(role.Permissions, PermAll) {
+			return nil
+		}
+	}
+	user := "anonymous"
+	if identity != nil {
+		user = identity.Name
+	}
+	return fmt.Errorf("%w: %s cannot view audit log", ErrForbidden, user)
+}
+
+// audit 记录请求的审计事件，err 为操作结果，没有权限视为被拒绝
+func (lv *LogViewer) audit(r *http.Request, action string, src *Source, file string, err error) {
+	event := AuditEvent{
+		IP:      lv.clientIP(r),
+		Action:  action,
+		File:    file,
+		Path:    r.URL.Path,
+		Outcome: AuditSuccess,
+	}
+	if identity := IdentityFrom(r.Context()); identity != nil {
+		event.User = identity.Name
+	}
+	if src != nil {
+		event.Source = src.config.Name
+	}
+	if err != nil {
+		event.Outcome = AuditFailed
+		if errors.Is(err, ErrForbidden) || errors.Is(err, ErrDisabled) || errors.Is(err, ErrUnauthorized) {
+			event.Outcome = AuditDenied
+		}
+		event.Error = err.Error()
+	}
+	lv.record(event)
+}
+
+// auditDenied 只在没有权限时记录审计事件，用于查看和跟踪等只读操作
+func (lv *LogViewer) auditDenied(r *http.Request, action string, src *Source, file string, err error) {
+	if errors.Is(err, ErrForbidden) || errors.Is(err, ErrDisabled) {
+		lv.audit(r, action, src, file, err)
+	}
+}
+
+// record 记录审计事件，写入失败时输出到默认日志，不影响请求
+func (lv *LogViewer) record(event AuditEvent) {
+	if err := lv.Audit(event); err != nil {
+		slog.Error("goslogviewer: failed to write audit event", "err", err, "action", event.Action, "file", event.File)
+	}
+}
+
+// AuditHandler 只读的审计日志接口，limit 指定返回条数（默认 100，最大 1000），最新的在前
+func (lv *LogViewer) AuditHandler(w http.ResponseWriter, r *http.Request) {
+	if err := lv.authorizeAudit(IdentityFrom(r.Context())); err != nil {
+		lv.audit(r, PermAudit, nil, "", err)
+		httpError(w, err)
+		return
+	}
+	limit := defaultAuditSize
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit: "+s, http.StatusBadRequest)
+			return
+		}
+		limit = min(n, auditRecentSize)
+	}
+	events, err := lv.AuditEvents(limit)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	respondJSON(w, map[string]interface{}{
+		"code": 200,
+		"data": events,
+		"msg":  "success",
+	})
+}
@@ -0,0 +1,250 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 23:05:37
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 23:05:37
+ * Description: 审计日志测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAudit_Handlers(t *testing.T) {
+	tempDir := t.TempDir()
+	appendLine(t, filepath.Join(tempDir, "app.log"), `{"level":"INFO","msg":"hello"}`+"\n")
+	auditFile := filepath.Join(tempDir, "audit.jsonl")
+	var logged bytes.Buffer
+
+	lv := New(&Config{
+		LogDir:         tempDir,
+		TrustedProxies: []string{"10.0.0.1"},
+		BearerTokens:   map[string]string{"oncall-token": "olga", "sre-token": "sam"},
+		Roles: []Role{
+			{Name: "oncall", Permissions: []string{PermView, PermExport}},
+			{Name: "sre", Permissions: []string{PermView, PermClear, PermAudit}},
+		},
+		UserRoles:   map[string][]string{"olga": {"oncall"}, "sam": {"sre"}},
+		AuditFile:   auditFile,
+		AuditLogger: slog.New(slog.NewJSONHandler(&logged, nil)),
+	})
+	handler := lv.Handler("/log")
+
+	do := func(method, path, token string, form url.Values) *httptest.ResponseRecorder {
+		var req *http.Request
+		if form != nil {
+			req = httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		} else {
+			req = httptest.NewRequest(method, path, nil)
+		}
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set("X-Forwarded-For", "192.168.1.20")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	do("GET", "/log/exportFile?name=app.log", "oncall-token", nil)
+	do("POST", "/log/clearFileContent", "oncall-token", url.Values{"name": {"app.log"}})
+	do("POST", "/log/clearFileContent", "sre-token", url.Values{"name": {"app.log"}})
+	do("GET", "/log/getFileContent?name=app.log", "wrong-token", nil)
+	// 查看成功不记录
+	do("GET", "/log/getFileContent?name=app.log", "sre-token", nil)
+
+	// 没有 audit 权限
+	if w := do("GET", "/log/audit", "oncall-token", nil); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403 for oncall, got %d", w.Code)
+	}
+
+	w := do("GET", "/log/audit", "sre-token", nil)
+	var response struct {
+		Code int          `json:"code"`
+		Data []AuditEvent `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil || response.Code != 200 {
+		t.Fatalf("Unexpected response: %d %v", w.Code, err)
+	}
+
+	// 最新的在前
+	want := []struct{ user, action, outcome string }{
+		{"olga", PermAudit, AuditDenied},
+		{"", ActionAccess, AuditDenied},
+		{"sam", PermClear, AuditSuccess},
+		{"olga", PermClear, AuditDenied},
+		{"olga", PermExport, AuditSuccess},
+	}
+	if len(response.Data) != len(want) {
+		t.Fatalf("Expected %d events, got %+v", len(want), response.Data)
+	}
+	for i, event := range response.Data {
+		if event.User != want[i].user || event.Action != want[i].action || event.Outcome != want[i].outcome {
+			t.Errorf("Event %d = %+v, want %+v", i, event, want[i])
+		}
+		if event.IP != "192.168.1.20" || event.Time.IsZero() {
+			t.Errorf("Event %d: unexpected ip or time: %+v", i, event)
+		}
+	}
+	if response.Data[2].File != "app.log" || response.Data[2].Source != DefaultSourceName {
+		t.Errorf("Unexpected clear event: %+v", response.Data[2])
+	}
+
+	if n := strings.Count(logged.String(), `"msg":"audit"`); n != len(want) {
+		t.Errorf("Expected %d slog records, got %d", len(want), n)
+	}
+
+	// 审计文件位于日志目录中时被隐藏，不能被清空
+	files, err := lv.defaultSource().As(&Identity{Name: "sam"}).GetLogFiles()
+	if err != nil || len(files) != 1 || files[0] != "app.log" {
+		t.Errorf("Audit file should be hidden: %v %v", files, err)
+	}
+	do("POST", "/log/clearFileContent", "sre-token", url.Values{"name": {"audit.jsonl"}})
+	if info, err := os.Stat(auditFile); err != nil || info.Size() == 0 {
+		t.Errorf("Audit file should not be cleared: %v", err)
+	}
+}
+
+func TestAudit_Symlink(t *testing.T) {
+	base := t.TempDir()
+	logDir := filepath.Join(base, "logs")
+	os.MkdirAll(logDir, 0755)
+	if err := os.Symlink(logDir, filepath.Join(base, "link")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	os.Symlink("audit.jsonl", filepath.Join(logDir, "alias.jsonl"))
+
+	// 审计文件经由链接目录配置，或通过日志目录中的符号链接访问时同样被隐藏
+	lv := New(&Config{LogDir: logDir, DevMode: true, EnableClear: true, AuditFile: filepath.Join(base, "link", "audit.jsonl")})
+	lv.Audit(AuditEvent{Action: PermDelete, Outcome: AuditSuccess})
+	for _, name := range []string{"audit.jsonl", "alias.jsonl"} {
+		if err := lv.ClearFileContent(name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("ClearFileContent(%q) = %v, want fs.ErrNotExist", name, err)
+		}
+	}
+	if info, err := os.Stat(filepath.Join(logDir, "audit.jsonl")); err != nil || info.Size() == 0 {
+		t.Errorf("Audit file should not be cleared: %v", err)
+	}
+}
+
+func TestAudit_WithoutRoles(t *testing.T) {
+	get := func(cfg *Config, token string) int {
+		cfg.LogDir = t.TempDir()
+		req := httptest.NewRequest("GET", "/log/audit", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		New(cfg).Handler("/log").ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 未配置角色时匿名用户不能查看审计日志，通过认证或开发模式下可以查看
+	if code := get(&Config{}, ""); code != http.StatusForbidden {
+		t.Errorf("Expected 403 for anonymous user, got %d", code)
+	}
+	if code := get(&Config{DevMode: true}, ""); code != http.StatusOK {
+		t.Errorf("Expected 200 in DevMode, got %d", code)
+	}
+	if code := get(&Config{BearerTokens: map[string]string{"token": "sam"}}, "token"); code != http.StatusOK {
+		t.Errorf("Expected 200 for authenticated user, got %d", code)
+	}
+}
+
+// failingWriter 写入响应体失败，模拟下载过程中客户端断开
+type failingWriter struct {
+	header http.Header
+}
+
+func (w *failingWriter) Header() http.Header       { return w.header }
+func (w *failingWriter) WriteHeader(int)           {}
+func (w *failingWriter) Write([]byte) (int, error) { return 0, errors.New("connection reset") }
+
+func TestAudit_BundleInterrupted(t *testing.T) {
+	tempDir := t.TempDir()
+	appendLine(t, filepath.Join(tempDir, "app.log"), `{"level":"INFO","msg":"hello"}`+"\n")
+	lv := New(&Config{LogDir: tempDir, EnableExport: true, AuditFile: filepath.Join(t.TempDir(), "audit.jsonl")})
+
+	// 打包中断时记录为失败，而不是在写入之前记录成功
+	lv.Handler("/log").ServeHTTP(&failingWriter{header: http.Header{}}, httptest.NewRequest("GET", "/log/exportBundle?name=app.log", nil))
+	events, err := lv.AuditEvents(10)
+	if err != nil || len(events) != 1 || events[0].File != "app.log" || events[0].Outcome != AuditFailed || events[0].Error == "" {
+		t.Fatalf("Unexpected events: %+v %v", events, err)
+	}
+
+	w := httptest.NewRecorder()
+	lv.Handler("/log").ServeHTTP(w, httptest.NewRequest("GET", "/log/exportBundle?name=app.log", nil))
+	if events, _ := lv.AuditEvents(1); w.Code != http.StatusOK || len(events) != 1 || events[0].Outcome != AuditSuccess {
+		t.Errorf("Unexpected events after successful bundle: %+v", events)
+	}
+}
+
+func TestAudit_FileIsAppendOnly(t *testing.T) {
+	auditFile := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	lv := New(&Config{LogDir: t.TempDir(), AuditFile: auditFile})
+	lv.Audit(AuditEvent{Action: PermDelete, Outcome: AuditSuccess})
+
+	// 重新创建 LogViewer 后继续追加，之前的记录仍可读取
+	lv = New(&Config{LogDir: t.TempDir(), AuditFile: auditFile})
+	lv.Audit(AuditEvent{Action: PermClear, File: "app.log", Outcome: AuditFailed})
+	events, err := lv.AuditEvents(10)
+	if err != nil || len(events) != 2 || events[0].Action != PermClear || events[1].Action != PermDelete {
+		t.Fatalf("Unexpected events: %+v %v", events, err)
+	}
+	if events, _ := lv.AuditEvents(1); len(events) != 1 || events[0].Action != PermClear {
+		t.Errorf("Unexpected limited events: %+v", events)
+	}
+}
+
+func TestAudit_ReadFromEnd(t *testing.T) {
+	auditFile := filepath.Join(t.TempDir(), "audit.jsonl")
+	lv := New(&Config{LogDir: t.TempDir(), AuditFile: auditFile})
+	lv.Audit(AuditEvent{Action: PermDelete, File: "first.log", Outcome: AuditSuccess})
+	// 超长行与无法解析的行被跳过，不影响其前后的事件
+	appendLine(t, auditFile, `{"action":"`+strings.Repeat("x", maxAuditLine)+`"}`+"\n"+"not json\n")
+	for i := 0; i < 2000; i++ {
+		lv.Audit(AuditEvent{Action: PermView, File: fmt.Sprintf("app-%04d.log", i), Outcome: AuditSuccess})
+	}
+
+	// 事件跨越多个读取块时仍按最新在前的顺序返回
+	events, err := lv.AuditEvents(1000)
+	if err != nil || len(events) != 1000 {
+		t.Fatalf("Unexpected events: %d %v", len(events), err)
+	}
+	for i, event := range events {
+		if want := fmt.Sprintf("app-%04d.log", 1999-i); event.File != want {
+			t.Fatalf("events[%d].File = %q, want %q", i, event.File, want)
+		}
+	}
+
+	events, err = readAuditFile(auditFile, 3000)
+	if err != nil || len(events) != 2001 || events[0].File != "first.log" || events[2000].File != "app-1999.log" {
+		t.Errorf("Unexpected events: %d %v", len(events), err)
+	}
+}
+
+func TestAudit_IPRestriction(t *testing.T) {
+	lv := New(&Config{LogDir: t.TempDir(), EnableIPRestriction: true, AllowedIPs: []string{"192.168.1.0/24"}})
+	req := httptest.NewRequest("GET", "/log/getSources", nil)
+	req.RemoteAddr = "172.16.0.5:1234"
+	lv.Handler("/log").ServeHTTP(httptest.NewRecorder(), req)
+
+	events, _ := lv.AuditEvents(0)
+	if len(events) != 1 || events[0].IP != "172.16.0.5" || events[0].Action != ActionAccess || events[0].Outcome != AuditDenied {
+		t.Errorf("Unexpected events: %+v", events)
+	}
+}
@@ -0,0 +1,180 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 21:02:35
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 21:02:35
+ * Description: 身份认证
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrUnauthorized 认证信息无效
+var ErrUnauthorized = errors.New("unauthorized")
+
+// 认证方式
+const (
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+)
+
+// Identity 已认证的用户
+type Identity struct {
+	Name   string   `json:"name"`
+	Method string   `json:"method"`          // 认证方式：basic、bearer 或自定义认证器的名称
+	Roles  []string `json:"roles,omitempty"` // 角色，自定义认证器可直接给出，也可通过 Config.UserRoles 配置
+}
+
+// Authenticator 认证接口，可用于接入 SSO 等自定义认证。
+// 请求中没有对应的认证信息时返回 (nil, nil)，交由下一个认证器处理；认证信息无效时返回错误
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Identity, error)
+}
+
+// AuthenticatorFunc 函数形式的 Authenticator
+type AuthenticatorFunc func(r *http.Request) (*Identity, error)
+
+func (f AuthenticatorFunc) Authenticate(r *http.Request) (*Identity, error) {
+	return f(r)
+}
+
+// BasicAuthenticator HTTP Basic 认证，Users 的值为 bcrypt 哈希
+type BasicAuthenticator struct {
+	Users map[string]string
+}
+
+// 用户不存在时用于比较的哈希，使耗时与密码错误时一致
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("goslogviewer"), bcrypt.DefaultCost)
+	return hash
+})
+
+func (a *BasicAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, nil
+	}
+	hash, exists := a.Users[user]
+	if !exists {
+		bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+		return nil, ErrUnauthorized
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return nil, ErrUnauthorized
+	}
+	return &Identity{Name: user, Method: AuthBasic}, nil
+}
+
+// TokenAuthenticator 静态 Bearer Token 认证，Tokens 的键为 token，值为对应的用户名
+type TokenAuthenticator struct {
+	Tokens map[string]string
+}
+
+func (a *TokenAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, nil
+	}
+	token = strings.TrimSpace(token)
+	for want, name := range a.Tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1 {
+			return &Identity{Name: name, Method: AuthBearer}, nil
+		}
+	}
+	return nil, ErrUnauthorized
+}
+
+// authenticators 返回配置的认证器，未配置任何认证时返回 nil
+func (lv *LogViewer) authenticators() []Authenticator {
+	var list []Authenticator
+	if len(lv.config.BasicAuth) > 0 {
+		list = append(list, &BasicAuthenticator{Users: lv.config.BasicAuth})
+	}
+	if len(lv.config.BearerTokens) > 0 {
+		list = append(list, &TokenAuthenticator{Tokens: lv.config.BearerTokens})
+	}
+	return append(list, lv.config.Authenticators...)
+}
+
+// AuthEnabled 判断是否启用了认证
+func (lv *LogViewer) AuthEnabled() bool {
+	return len(lv.authenticators()) > 0
+}
+
+// Authenticate 依次使用各认证器认证请求，未启用认证时返回 (nil, nil)
+func (lv *LogViewer) Authenticate(r *http.Request) (*Identity, error) {
+	list := lv.authenticators()
+	if len(list) == 0 {
+		return nil, nil
+	}
+	for _, a := range list {
+		identity, err := a.Authenticate(r)
+		if err != nil {
+			return nil, err
+		}
+		if identity != nil {
+			return identity, nil
+		}
+	}
+	return nil, ErrUnauthorized
+}
+
+type identityKey struct{}
+
+// WithIdentity 将用户保存到 context
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFrom 获取 context 中的用户，未认证时返回 nil
+func IdentityFrom(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(identityKey{}).(*Identity)
+	return identity
+}
+
+// requireAuth 认证失败时返回 401，成功时将用户保存到请求的 context
+func (lv *LogViewer) requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, err := lv.Authenticate(r)
+		if err != nil {
+			// 携带了认证信息但认证失败时记录审计事件，未登录的请求不记录
+			if r.Header.Get("Authorization") != "" || !errors.Is(err, ErrUnauthorized) {
+				user, _, _ := r.BasicAuth()
+				lv.record(AuditEvent{
+					IP:      lv.clientIP(r),
+					User:    user,
+					Action:  ActionAccess,
+					Path:    r.URL.Path,
+					Outcome: AuditDenied,
+					Error:   err.Error(),
+				})
+			}
+			// 页面通过 XMLHttpRequest/fetch 请求时不返回 WWW-Authenticate，避免浏览器弹出自带的登录框
+			if r.Header.Get("X-Requested-With") == "" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="goslogviewer"`)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			respondJSON(w, map[string]interface{}{
+				"code": 401,
+				"data": nil,
+				"msg":  err.Error(),
+			})
+			return
+		}
+		if identity != nil {
+			r = r.WithContext(WithIdentity(r.Context(), identity))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,120 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 21:30:12
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 21:30:12
+ * Description: 身份认证测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHandler_Auth(t *testing.T) {
+	tempDir := t.TempDir()
+	appendLine(t, filepath.Join(tempDir, "app.log"), `{"level":"INFO","msg":"hello"}`+"\n")
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+
+	sso := AuthenticatorFunc(func(r *http.Request) (*Identity, error) {
+		switch r.Header.Get("X-SSO-User") {
+		case "":
+			return nil, nil
+		case "mallory":
+			return nil, errors.New("session expired")
+		default:
+			return &Identity{Name: r.Header.Get("X-SSO-User"), Method: "sso"}, nil
+		}
+	})
+	lv := New(&Config{
+		LogDir:         tempDir,
+		BasicAuth:      map[string]string{"alice": string(hash)},
+		BearerTokens:   map[string]string{"t0ken": "ci"},
+		Authenticators: []Authenticator{sso},
+	})
+	handler := lv.Handler("/log")
+
+	tests := []struct {
+		name   string
+		path   string
+		header func(r *http.Request)
+		status int
+	}{
+		{"Page is public", "/log", nil, http.StatusOK},
+		{"Static is public", "/log/static/dashboard.css", nil, http.StatusOK},
+		{"No credentials", "/log/getLogFilesList", nil, http.StatusUnauthorized},
+		{"Basic", "/log/getLogFilesList", func(r *http.Request) { r.SetBasicAuth("alice", "secret") }, http.StatusOK},
+		{"Basic wrong password", "/log/getLogFilesList", func(r *http.Request) { r.SetBasicAuth("alice", "wrong") }, http.StatusUnauthorized},
+		{"Basic unknown user", "/log/getLogFilesList", func(r *http.Request) { r.SetBasicAuth("bob", "secret") }, http.StatusUnauthorized},
+		{"Bearer", "/log/getFileContent?name=app.log", func(r *http.Request) { r.Header.Set("Authorization", "Bearer t0ken") }, http.StatusOK},
+		{"Bearer wrong token", "/log/getFileContent?name=app.log", func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") }, http.StatusUnauthorized},
+		{"Custom", "/log/getSources", func(r *http.Request) { r.Header.Set("X-SSO-User", "carol") }, http.StatusOK},
+		{"Custom rejected", "/log/getSources", func(r *http.Request) { r.Header.Set("X-SSO-User", "mallory") }, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.header != nil {
+				tt.header(req)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d", tt.status, w.Code)
+			}
+			if tt.status == http.StatusUnauthorized && !strings.Contains(w.Body.String(), `"code":401`) {
+				t.Errorf("Unexpected body: %s", w.Body.String())
+			}
+		})
+	}
+
+	// 认证后的用户保存在请求的 context 中
+	var seen *Identity
+	wrapped := lv.requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = IdentityFrom(r.Context())
+	}))
+	req := httptest.NewRequest("GET", "/log/getSources", nil)
+	req.SetBasicAuth("alice", "secret")
+	wrapped.ServeHTTP(httptest.NewRecorder(), req)
+	if seen == nil || seen.Name != "alice" || seen.Method != AuthBasic {
+		t.Errorf("Unexpected identity: %+v", seen)
+	}
+}
+
+func TestHandler_AuthPrompt(t *testing.T) {
+	lv := New(&Config{LogDir: t.TempDir(), BearerTokens: map[string]string{"t0ken": "ci"}})
+	handler := lv.Handler("/log")
+
+	// 浏览器直接访问接口时提示 Basic 认证，页面中的请求不提示
+	req := httptest.NewRequest("GET", "/log/getSources", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Header().Get("WWW-Authenticate") == "" {
+		t.Error("Expected WWW-Authenticate header")
+	}
+	req.Header.Set("X-Requested-With", "XMLHttpRequest")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") != "" {
+		t.Errorf("Unexpected response for XHR: %d %v", w.Code, w.Header())
+	}
+
+	// 页面显示登录框及退出按钮
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/log", nil))
+	if body := w.Body.String(); !strings.Contains(body, `id="login"`) || strings.Contains(body, `id="sign_out" style="display: none;"`) {
+		t.Error("Expected login prompt and sign out link")
+	}
+
+	if New(&Config{}).AuthEnabled() {
+		t.Error("Auth should be disabled by default")
+	}
+}
@@ -0,0 +1,292 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/18 01:58:33
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/18 01:58:33
+ * Description: 将多个日志文件打包为 ZIP 或 tar.gz 导出，附带包含大小及 SHA-256 的清单
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"time"
+)
+
+// 打包格式
+const (
+	BundleZip   = "zip"
+	BundleTarGz = "tar.gz"
+)
+
+const (
+	bundleFilesDir = "files/"        // 日志文件在压缩包中的目录
+	bundleManifest = "manifest.json" // 清单文件名
+)
+
+// ErrNoFiles 没有符合条件的文件
+var ErrNoFiles = errors.New("no matching files")
+
+// BundleOptions 打包的文件选择条件：指定 Names 时只打包这些文件，
+// 否则打包日志源中（包括子目录）匹配 Glob 且修改时间在 [From, To] 内的文件
+type BundleOptions struct {
+	Names []string
+	Glob  string    // 文件名通配符，规则与 IncludePatterns 相同
+	From  time.Time // 修改时间下限，零值表示不限
+	To    time.Time // 修改时间上限，零值表示不限
+}
+
+// BundleManifest 压缩包中的清单
+type BundleManifest struct {
+	Source    string       `json:"source"`
+	CreatedAt time.Time    `json:"createdAt"`
+	Files     []BundleFile `json:"files"`
+}
+
+// BundleFile 清单中的文件
+type BundleFile struct {
+	Name    string    `json:"name"` // 相对日志目录的路径，在压缩包中位于 files/ 下
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	SHA256  string    `json:"sha256"`
+}
+
+// BundleFiles 返回要打包的文件，需要导出权限，角色限定了文件范围时只包含范围内的文件
+func (s *Source) BundleFiles(opts BundleOptions) ([]string, error) {
+	if err := s.authorize(PermExport, ""); err != nil {
+		return nil, err
+	}
+	if opts.Glob != "" {
+		if _, err := path.Match(opts.Glob, ""); err != nil {
+			return nil, fmt.Errorf("%w: glob %q", ErrInvalidPath, opts.Glob)
+		}
+	}
+
+	var names []string
+	if len(opts.Names) > 0 {
+		seen := map[string]bool{}
+		for _, name := range opts.Names {
+			if _, err := s.resolvePath(PermExport, name); err != nil {
+				return nil, err
+			}
+			clean, _ := cleanName(name)
+			if !seen[clean] {
+				seen[clean] = true
+				names = append(names, clean)
+			}
+		}
+		return names, nil
+	}
+
+	err := s.walkLogFiles(ListOptions{Recursive: true}, func(name string, info fs.FileInfo) error {
+		if opts.Glob != "" && !matchName(opts.Glob, name) {
+			return nil
+		}
+		if (!opts.From.IsZero() && info.ModTime().Before(opts.From)) || (!opts.To.IsZero() && info.ModTime().After(opts.To)) {
+			return nil
+		}
+		if s.Can(PermExport, name) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, ErrNoFiles
+	}
+	return names, nil
+}
+
+// WriteBundle 将文件按原样写入 ZIP 或 tar.gz 压缩包，最后写入清单。
+// 每个文件按打开时的大小写入，写入过程中追加的内容不会包含在内
+func (s *Source) WriteBundle(w io.Writer, format string, names []string) (*BundleManifest, error) {
+	manifest := &BundleManifest{Source: s.config.Name, CreatedAt: time.Now(), Files: []BundleFile{}}
+
+	var archive bundleWriter
+	switch format {
+	case BundleZip:
+		archive = &zipBundle{zw: zip.NewWriter(w)}
+	case BundleTarGz:
+		gz := gzip.NewWriter(w)
+		archive = &tarBundle{gz: gz, tw: tar.NewWriter(gz)}
+	default:
+		return nil, fmt.Errorf("unsupported bundle format: %s", format)
+	}
+
+	for _, name := range names {
+		file, err := s.bundleFile(archive, name)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := archive.add(bundleManifest, int64(len(data)), manifest.CreatedAt, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return manifest, archive.Close()
+}
+
+// bundleFile 将一个文件写入压缩包并计算 SHA-256
+func (s *Source) bundleFile(archive bundleWriter, name string) (BundleFile, error) {
+	file, _, err := s.open(PermExport, name)
+	if err != nil {
+		return BundleFile{}, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return BundleFile{}, err
+	}
+
+	hash := sha256.New()
+	r := io.TeeReader(io.LimitReader(file, info.Size()), hash)
+	if err := archive.add(bundleFilesDir+name, info.Size(), info.ModTime(), r); err != nil {
+		return BundleFile{}, err
+	}
+	return BundleFile{
+		Name:    name,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		SHA256:  hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// bundleWriter 压缩包写入接口
+type bundleWriter interface {
+	add(name string, size int64, modTime time.Time, r io.Reader) error
+	Close() error
+}
+
+type zipBundle struct {
+	zw *zip.Writer
+}
+
+func (b *zipBundle) add(name string, size int64, modTime time.Time, r io.Reader) error {
+	f, err := b.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime})
+	if err != nil {
+		return err
+	}
+	return copyExactly(f, r, size)
+}
+
+func (b *zipBundle) Close() error {
+	return b.zw.Close()
+}
+
+type tarBundle struct {
+	gz *gzip.Writer
+	tw *tar.Writer
+}
+
+func (b *tarBundle) add(name string, size int64, modTime time.Time, r io.Reader) error {
+	err := b.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     0644,
+		ModTime:  modTime,
+		Format:   tar.FormatPAX,
+	})
+	if err != nil {
+		return err
+	}
+	return copyExactly(b.tw, r, size)
+}
+
+func (b *tarBundle) Close() error {
+	if err := b.tw.Close(); err != nil {
+		return err
+	}
+	return b.gz.Close()
+}
+
+// copyExactly 复制 size 字节，文件在打开后被截断时返回错误
+func copyExactly(w io.Writer, r io.Reader, size int64) error {
+	n, err := io.Copy(w, r)
+	if err == nil && n != size {
+		err = fmt.Errorf("file changed while exporting: read %d of %d bytes", n, size)
+	}
+	return err
+}
+
+// ExportBundleHandler 将多个文件打包导出：name 可重复指定文件，或以 glob 及 from/to（文件修改时间）选择文件，
+// format 为 zip（默认）或 tar.gz
+func (lv *LogViewer) ExportBundleHandler(w http.ResponseWriter, r *http.Request) {
+	src, err := lv.requestSource(r)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = BundleZip
+	}
+	if format != BundleZip && format != BundleTarGz {
+		http.Error(w, "invalid format: "+format, http.StatusBadRequest)
+		return
+	}
+
+	opts := BundleOptions{Names: query["name"], Glob: query.Get("glob")}
+	for key, t := range map[string]*time.Time{"from": &opts.From, "to": &opts.To} {
+		if s := query.Get(key); s != "" {
+			if *t, err = ParseTime(s); err != nil {
+				http.Error(w, fmt.Sprintf("invalid %s: %s", key, s), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	if len(opts.Names) == 0 && opts.Glob == "" && opts.From.IsZero() && opts.To.IsZero() {
+		http.Error(w, "name, glob, from or to is required", http.StatusBadRequest)
+		return
+	}
+
+	names, err := src.BundleFiles(opts)
+	if err != nil {
+		lv.audit(r, PermExport, src, query.Get("glob"), err)
+		switch {
+		case errors.Is(err, ErrNoFiles), errors.Is(err, fs.ErrNotExist):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			httpError(w, err)
+		}
+		return
+	}
+	filename := fmt.Sprintf("%s-logs-%s.%s", src.config.Name, time.Now().Format("20060102-150405"), format)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.Header().Set("Cache-Control", "no-store")
+	if format == BundleZip {
+		w.Header().Set("Content-Type", "application/zip")
+	} else {
+		w.Header().Set("Content-Type", "application/gzip")
+	}
+	// 响应已开始发送，无法再返回错误状态，打包中断时审计记录为失败
+	_, err = src.WriteBundle(w, format, names)
+	if err != nil {
+		slog.Error("goslogviewer: bundle export interrupted", "source", src.config.Name, "err", err)
+	}
+	for _, name := range names {
+		lv.audit(r, PermExport, src, name, err)
+	}
+}
@@ -0,0 +1,169 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/18 02:21:05
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/18 02:21:05
+ * Description: 打包导出测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// bundleModTimes 打包测试文件的修改时间
+var bundleModTimes = map[string]time.Time{
+	"app.log":            time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC),
+	"api/2026-10-16.log": time.Date(2026, 10, 16, 23, 0, 0, 0, time.UTC),
+	"api/2026-10-15.log": time.Date(2026, 10, 15, 23, 0, 0, 0, time.UTC),
+	"worker.txt":         time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC),
+}
+
+// bundleFiles 打包测试文件
+var bundleFiles = infoFiles("app.log", "api/2026-10-16.log", "api/2026-10-15.log", "worker.txt")
+
+// readBundle 读取压缩包中的全部文件
+func readBundle(t *testing.T, format string, body []byte) map[string][]byte {
+	t.Helper()
+	files := map[string][]byte{}
+	if format == BundleZip {
+		zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+		if err != nil {
+			t.Fatalf("Invalid zip: %v", err)
+		}
+		for _, f := range zr.File {
+			rc, _ := f.Open()
+			files[f.Name], _ = io.ReadAll(rc)
+			rc.Close()
+		}
+		return files
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Invalid gzip: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Invalid tar: %v", err)
+		}
+		files[h.Name], _ = io.ReadAll(tr)
+	}
+	return files
+}
+
+func TestExportBundleHandler(t *testing.T) {
+	lv := newTestViewer(t, bundleFiles, &Config{EnableExport: true})
+	for name, modTime := range bundleModTimes {
+		setModTime(t, lv, name, modTime)
+	}
+	handler := lv.Handler("/log")
+
+	tests := []struct {
+		name   string
+		query  string
+		format string
+		want   []string
+	}{
+		{"Names", "name=app.log&name=api/2026-10-15.log&name=app.log", BundleZip, []string{"api/2026-10-15.log", "app.log"}},
+		{"Glob", "glob=*.log&format=tar.gz", BundleTarGz, []string{"api/2026-10-15.log", "api/2026-10-16.log", "app.log"}},
+		{"Time window", "from=2026-10-16T00:00:00Z&to=2026-10-16T23:59:59Z", BundleZip, []string{"api/2026-10-16.log", "app.log", "worker.txt"}},
+		{"Glob and time window", "glob=api/*.log&from=2026-10-16T00:00:00Z", BundleZip, []string{"api/2026-10-16.log"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/log/exportBundle?"+tt.query, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("Unexpected status %d: %s", w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Header().Get("Content-Disposition"), "."+tt.format) {
+				t.Errorf("Unexpected Content-Disposition: %s", w.Header().Get("Content-Disposition"))
+			}
+
+			files := readBundle(t, tt.format, w.Body.Bytes())
+			var manifest BundleManifest
+			if err := json.Unmarshal(files["manifest.json"], &manifest); err != nil {
+				t.Fatalf("Invalid manifest: %v", err)
+			}
+			var names []string
+			for _, f := range manifest.Files {
+				names = append(names, f.Name)
+				content := files["files/"+f.Name]
+				sum := sha256.Sum256(content)
+				if f.Size != int64(len(content)) || f.SHA256 != hex.EncodeToString(sum[:]) {
+					t.Errorf("Manifest entry does not match content: %+v", f)
+				}
+			}
+			sort.Strings(names)
+			if strings.Join(names, ",") != strings.Join(tt.want, ",") || len(files) != len(tt.want)+1 {
+				t.Errorf("Expected %v, got %v", tt.want, names)
+			}
+		})
+	}
+
+	failures := []struct {
+		query  string
+		status int
+	}{
+		{"", http.StatusBadRequest},
+		{"glob=*.log&format=rar", http.StatusBadRequest},
+		{"glob=[", http.StatusBadRequest},
+		{"from=yesterday", http.StatusBadRequest},
+		{"name=../secret.log", http.StatusBadRequest},
+		{"name=missing.log", http.StatusNotFound},
+		{"glob=*.gz", http.StatusNotFound},
+	}
+	for _, tt := range failures {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/log/exportBundle?"+tt.query, nil))
+		if w.Code != tt.status {
+			t.Errorf("%q: expected status %d, got %d", tt.query, tt.status, w.Code)
+		}
+	}
+}
+
+func TestExportBundle_Permissions(t *testing.T) {
+	lv := newTestViewer(t, bundleFiles, &Config{
+		Roles:     []Role{{Name: "api", Permissions: []string{PermView, PermExport}, Files: []string{"api/*"}}},
+		UserRoles: map[string][]string{"ann": {"api"}},
+	})
+
+	// 只包含角色范围内的文件
+	s := lv.defaultSource().As(&Identity{Name: "ann"})
+	names, err := s.BundleFiles(BundleOptions{Glob: "*"})
+	if err != nil || len(names) != 2 {
+		t.Errorf("Unexpected files: %v %v", names, err)
+	}
+	if _, err := s.BundleFiles(BundleOptions{Names: []string{"app.log"}}); err == nil {
+		t.Error("Expected file outside role scope to be rejected")
+	}
+	if _, err := lv.defaultSource().BundleFiles(BundleOptions{Glob: "*"}); err == nil {
+		t.Error("Expected anonymous bundle to be rejected")
+	}
+	// 未配置角色时需要 EnableExport
+	lv = newTestViewer(t, bundleFiles, nil)
+	w := httptest.NewRecorder()
+	lv.Handler("/log").ServeHTTP(w, httptest.NewRequest("GET", "/log/exportBundle?glob=*", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 when export is disabled, got %d", w.Code)
+	}
+}
@@ -0,0 +1,161 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 14:10:38
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 14:10:38
+ * Description: 压缩日志文件读取
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// 压缩格式
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// compressionOf 根据扩展名判断压缩格式，未压缩返回空字符串
+func compressionOf(name string) string {
+	switch lower := strings.ToLower(name); {
+	case strings.HasSuffix(lower, ".gz"):
+		return CompressionGzip
+	case strings.HasSuffix(lower, ".zst"), strings.HasSuffix(lower, ".zstd"):
+		return CompressionZstd
+	}
+	return ""
+}
+
+// logFile 打开的日志文件，压缩文件按解压后的内容读取，偏移量均为解压后的位置
+type logFile struct {
+	logHandle
+	compression string
+	memory      bool // 内存缓冲区的快照，最早的内容可能被丢弃，不是只追加的文件
+}
+
+// logHandle 磁盘文件或内存缓冲区快照
+type logHandle interface {
+	io.ReadSeekCloser
+	io.ReaderAt
+	Stat() (fs.FileInfo, error)
+}
+
+// open 检查权限后通过 os.Root 只读打开日志源中的文件，返回文件及其路径，内存日志源返回缓冲区的快照
+func (s *Source) open(perm, name string) (*logFile, string, error) {
+	if s.memory != nil {
+		return s.openMemory(perm, name)
+	}
+	file, path, err := s.openFile(perm, name, os.O_RDONLY)
+	if err != nil {
+		return nil, "", err
+	}
+	return &logFile{logHandle: file, compression: compressionOf(path)}, path, nil
+}
+
+// reader 返回从 offset 处开始读取的内容，压缩文件只能从头解压并跳过之前的内容
+func (f *logFile) reader(offset int64) (io.ReadCloser, error) {
+	if f.compression == "" {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return io.NopCloser(f.logHandle), nil
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	r, err := decompress(f.compression, f.logHandle)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		if _, err := io.CopyN(io.Discard, r, offset); err != nil && err != io.EOF {
+			r.Close()
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// sample 读取文件开头用于识别格式的内容
+func (f *logFile) sample() []byte {
+	if f.compression == "" {
+		return readSample(f.logHandle)
+	}
+	r, err := f.reader(0)
+	if err != nil {
+		return nil
+	}
+	defer r.Close()
+	buf := make([]byte, sampleSize)
+	n, _ := io.ReadFull(r, buf)
+	return buf[:n]
+}
+
+// complete 判断 end 之前的内容是否以换行符结尾，压缩文件不会再追加内容
+func (f *logFile) complete(end int64) bool {
+	if f.compression != "" {
+		return true
+	}
+	return complete(f.logHandle, end)
+}
+
+// readAll 读取文件全部内容，压缩文件返回解压后的内容
+func (f *logFile) readAll() ([]byte, error) {
+	r, err := f.reader(0)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// decompress 按压缩格式包装解压读取器
+func decompress(compression string, r io.Reader) (io.ReadCloser, error) {
+	switch compression {
+	case CompressionGzip:
+		return gzip.NewReader(r)
+	case CompressionZstd:
+		dec, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	}
+	return io.NopCloser(r), nil
+}
+
+// uncompressedSize 返回压缩文件的原始大小，未知时返回 -1，文件经 openFile 打开，不会越出日志目录。
+// gzip 末尾的 ISIZE 只是最后一个成员的大小且按 4GiB 取模，不能作为准确大小，而每次列出文件都解压代价过高，
+// 因此 gzip 文件返回 -1，在统计条数时由分页索引给出；zstd 取首个帧头中的内容大小
+func (s *Source) uncompressedSize(name, compression string, size int64) int64 {
+	if compression == CompressionGzip {
+		return -1
+	}
+	file, _, err := s.openFile(PermView, name, os.O_RDONLY)
+	if err != nil {
+		return -1
+	}
+	defer file.Close()
+
+	switch compression {
+	case CompressionZstd:
+		head := make([]byte, zstd.HeaderMaxSize)
+		n, _ := file.ReadAt(head, 0)
+		var h zstd.Header
+		if err := h.Decode(head[:n]); err != nil || !h.HasFCS {
+			return -1
+		}
+		return int64(h.FrameContentSize)
+	}
+	return size
+}
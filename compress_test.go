@@ -0,0 +1,155 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 14:46:09
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 14:46:09
+ * Description: 压缩文件测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// writeCompressed 生成 n 条日志并按扩展名压缩写入，返回原始内容
+func writeCompressed(t *testing.T, path string, n int) []byte {
+	t.Helper()
+	var raw bytes.Buffer
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&raw, `{"time":"2023-01-01T00:00:00Z","level":"INFO","msg":"msg-%d"}`+"\n", i)
+	}
+
+	var out bytes.Buffer
+	switch compressionOf(path) {
+	case CompressionGzip:
+		zw := gzip.NewWriter(&out)
+		zw.Write(raw.Bytes())
+		zw.Close()
+	case CompressionZstd:
+		zw, _ := zstd.NewWriter(&out)
+		zw.Write(raw.Bytes())
+		zw.Close()
+	}
+	if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	return raw.Bytes()
+}
+
+func TestCompressedContent(t *testing.T) {
+	for _, name := range []string{"app-2026-10-01.log.gz", "app-2026-10-01.log.zst"} {
+		t.Run(name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			lv := New(&Config{LogDir: tempDir, EnableExport: true})
+			raw := writeCompressed(t, filepath.Join(tempDir, name), 1500)
+
+			entries, err := lv.GetLogContent(name, &Filter{Contains: "msg-149"})
+			if err != nil {
+				t.Fatalf("GetLogContent failed: %v", err)
+			}
+			if len(entries) != 11 {
+				t.Errorf("Expected 11 entries, got %d", len(entries))
+			}
+
+			page, err := lv.GetLogPage(name, PageQuery{Page: 121, PageSize: 10})
+			if err != nil {
+				t.Fatalf("GetLogPage failed: %v", err)
+			}
+			if page.Total != 1500 || page.Entries[0].Msg != "msg-1200" {
+				t.Errorf("Unexpected page: total %d, first %s", page.Total, page.Entries[0].Msg)
+			}
+			next, err := lv.GetLogPage(name, PageQuery{Cursor: page.NextCursor, PageSize: 10})
+			if err != nil {
+				t.Fatalf("GetLogPage with cursor failed: %v", err)
+			}
+			if next.Entries[0].Msg != "msg-1210" {
+				t.Errorf("Expected msg-1210 after cursor, got %s", next.Entries[0].Msg)
+			}
+
+			var events []string
+			err = lv.Tail(context.Background(), name, &Filter{Contains: "msg-14"}, func(event TailEvent) error {
+				events = append(events, event.Type)
+				return nil
+			})
+			if err != nil || len(events) != 112 || events[111] != TailEOF {
+				t.Errorf("Unexpected tail result: %d events, err %v", len(events), err)
+			}
+
+			// gzip 的原始大小只在统计条数时给出
+			files, err := lv.ListLogFiles(ListOptions{Stats: true})
+			if err != nil || len(files) != 1 {
+				t.Fatalf("ListLogFiles failed: %v, %v", files, err)
+			}
+			if files[0].UncompressedSize != int64(len(raw)) || files[0].Size >= files[0].UncompressedSize {
+				t.Errorf("Unexpected sizes: %+v", files[0])
+			}
+
+			req := httptest.NewRequest("GET", "/log/exportFile?name="+name, nil)
+			w := httptest.NewRecorder()
+			lv.ExportFileHandler(w, req)
+			var response struct {
+				Data string `json:"data"`
+			}
+			json.NewDecoder(w.Body).Decode(&response)
+			if response.Data != string(raw) {
+				t.Errorf("Expected decompressed export, got %d bytes", len(response.Data))
+			}
+		})
+	}
+}
+
+func TestListLogFiles_Uncompressed(t *testing.T) {
+	tempDir := t.TempDir()
+	lv := New(&Config{LogDir: tempDir})
+	os.WriteFile(filepath.Join(tempDir, "app.log"), []byte(strings.Repeat("x", 42)), 0644)
+
+	files, err := lv.ListLogFiles(ListOptions{})
+	if err != nil {
+		t.Fatalf("ListLogFiles failed: %v", err)
+	}
+	if files[0].Compression != "" || files[0].Size != 42 || files[0].UncompressedSize != 42 {
+		t.Errorf("Unexpected file info: %+v", files[0])
+	}
+}
+
+func TestUncompressedSize_Gzip(t *testing.T) {
+	tempDir := t.TempDir()
+	lv := New(&Config{LogDir: tempDir})
+
+	// 多个成员：ISIZE 只是最后一个成员的大小
+	var multi bytes.Buffer
+	for i := 0; i < 2; i++ {
+		zw := gzip.NewWriter(&multi)
+		fmt.Fprintf(zw, `{"time":"2023-01-01T00:00:00Z","level":"INFO","msg":"member-%d"}`+"\n", i)
+		zw.Close()
+	}
+	os.WriteFile(filepath.Join(tempDir, "multi.log.gz"), multi.Bytes(), 0644)
+
+	// 列出文件时不解压 gzip，大小未知
+	files, err := lv.ListLogFiles(ListOptions{Sort: SortByName})
+	if err != nil || len(files) != 1 {
+		t.Fatalf("ListLogFiles failed: %v, %v", files, err)
+	}
+	if files[0].UncompressedSize != -1 {
+		t.Errorf("Expected unknown size for gzip, got %+v", files[0])
+	}
+
+	// 统计条数时读完文件，给出全部成员的准确大小
+	lineSize := int64(len(`{"time":"2023-01-01T00:00:00Z","level":"INFO","msg":"member-0"}` + "\n"))
+	files, err = lv.ListLogFiles(ListOptions{Sort: SortByName, Stats: true})
+	if err != nil || files[0].UncompressedSize != 2*lineSize {
+		t.Errorf("Expected exact size with stats, got %+v %v", files[0], err)
+	}
+}
@@ -8,27 +8,64 @@
  */
 package goslogviewer
 
+import (
+	"log/slog"
+	"time"
+)
+
 type Config struct {
-	DevMode             bool     // 是否开发模式
-	LogDir              string   // 日志目录路径
-	EnableIPRestriction bool     // 是否启用IP限制（默认false）
-	TrustedProxies      []string // 可信代理IP列表（用于获取真实客户端IP）
-	AllowedIPs          []string // 允许访问的IP列表
-	EnableDelete        bool     // 是否启用删除功能
-	EnableExport        bool     // 是否启用导出功能
-	EnableClear         bool     // 是否启用清除功能
-	PageSize            int      // 每页显示条数
+	DevMode             bool          // 是否开发模式
+	LogDir              string        // 日志目录路径
+	BasePath            string        // 适配器注册的路径前缀（默认 /log），页面中的静态资源及接口地址随之变化
+	EnableIPRestriction bool          // 是否启用IP限制（默认false）
+	TrustedProxies      []string      // 可信代理IP列表（用于获取真实客户端IP）
+	AllowedIPs          []string      // 允许访问的IP列表
+	EnableDelete        bool          // 是否启用删除功能
+	EnableExport        bool          // 是否启用导出功能
+	EnableClear         bool          // 是否启用清除功能
+	PageSize            int           // 每页显示条数
+	IndexCacheSize      int           // 内存中缓存分页索引的最大文件数，默认 256，超出时丢弃最久未使用的
+	TailInterval        time.Duration // 实时跟踪时检查文件变化的间隔（默认500ms）
+
+	Format      string                // 日志格式：auto（默认，自动识别）、json、text、plain 或 Parsers 中的名称
+	FileFormats map[string]string     // 按文件名通配符指定格式，如 {"worker-*.log": "text"}
+	Parsers     map[string]LineParser // 自定义解析器，键为格式名称
+
+	IncludePatterns []string // 只显示匹配的文件，如 {"*.log", "*.log.gz"}，为空时显示全部
+	ExcludePatterns []string // 隐藏匹配的文件或目录，如 {"*.pid", "*.lock", "core*"}
+
+	BasicAuth      map[string]string // HTTP Basic 认证，键为用户名，值为 bcrypt 哈希
+	BearerTokens   map[string]string // Bearer Token 认证，键为 token，值为用户名
+	Authenticators []Authenticator   // 自定义认证器，在 BasicAuth 和 BearerTokens 之后依次尝试
+
+	Roles     []Role              // 角色，配置后按角色检查各项操作，取代 DevMode 及 Enable* 开关
+	UserRoles map[string][]string // 用户名对应的角色名称
+
+	Sources []SourceConfig // 多个日志源，配置后忽略 LogDir 及 Enable* 开关，第一个为默认日志源
+
+	AuditLogger *slog.Logger // 审计日志输出，记录清空、删除、导出及被拒绝的访问
+	AuditFile   string       // 审计文件路径，只追加写入，/log/audit 接口从中读取
+
+	Memory           *RingBuffer // 内存缓冲区，配置后增加一个内存日志源，配合 NewRingHandler 使用
+	MemorySourceName string      // 内存日志源的名称，默认 memory，不能与其他日志源重名
+
+	IndexFile     string        // 持久索引文件路径（bbolt），配置后搜索及带过滤条件的分页使用索引，见 Indexer
+	IndexAttrs    []string      // 建立索引的属性键，分组属性用 "." 连接，如 request_id、req.method
+	IndexInterval time.Duration // Indexer.Run 的更新间隔，默认 1 分钟
 }
 
 // DefaultConfig 返回默认配置
 func DefaultConfig() *Config {
 	return &Config{
 		LogDir:       "./log",
+		BasePath:     defaultBasePath,
 		DevMode:      false,
 		EnableDelete: false,
 		EnableExport: true,
 		EnableClear:  false,
 		PageSize:     10,
+		TailInterval: defaultTailInterval,
+		Format:       FormatAuto,
 		AllowedIPs:   []string{"127.0.0.1"},
 	}
 }
@@ -9,85 +9,398 @@
 package goslogviewer
 
 import (
-	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// slog 内置字段名
+const (
+	levelKey = "level"
+	timeKey  = "time"
+	msgKey   = "msg"
 )
 
 type LogEntry struct {
-	Level string `json:"level"`
-	Time  string `json:"time"`
-	Msg   string `json:"msg"`
+	Level string                 `json:"level"`
+	Time  string                 `json:"time"`
+	Msg   string                 `json:"msg"`
+	Attrs map[string]interface{} `json:"attrs,omitempty"` // 其余属性，分组保持嵌套结构
+}
+
+// parseJSONEntry 将一行 slog JSON 日志解析为 LogEntry
+func parseJSONEntry(line []byte) (LogEntry, bool) {
+	var fields map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber() // 保留数字原样，避免大整数丢失精度
+	if err := dec.Decode(&fields); err != nil || fields == nil {
+		return LogEntry{}, false
+	}
+	// 一行只能包含一个 JSON 对象，之后有其他内容时不是 JSON 日志
+	if _, err := dec.Token(); err != io.EOF {
+		return LogEntry{}, false
+	}
+
+	entry := LogEntry{
+		Level: stringField(fields, levelKey),
+		Time:  stringField(fields, timeKey),
+		Msg:   stringField(fields, msgKey),
+	}
+	if len(fields) > 0 {
+		entry.Attrs = fields
+	}
+	return entry, true
+}
+
+// stringField 取出并删除指定字段，非字符串值按默认格式转换
+func stringField(fields map[string]interface{}, key string) string {
+	v, ok := fields[key]
+	if !ok {
+		return ""
+	}
+	delete(fields, key)
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
 }
 
 // GetLogFiles 获取日志文件列表
-func (lv *LogViewer) GetLogFiles() ([]string, error) {
+func (s *Source) GetLogFiles() ([]string, error) {
+	if err := s.authorize(PermView, ""); err != nil {
+		return nil, err
+	}
 	var files []string
-	entries, err := os.ReadDir(lv.config.LogDir)
+	if s.memory != nil {
+		if s.Can(PermView, MemoryFileName) {
+			files = append(files, MemoryFileName)
+		}
+		return files, nil
+	}
+	entries, err := os.ReadDir(s.config.Dir)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		if !entry.IsDir() && !s.hidden(entry.Name()) && s.Can(PermView, entry.Name()) {
 			files = append(files, entry.Name())
 		}
 	}
 	return files, nil
 }
 
-// GetLogContent 获取日志内容
-func (lv *LogViewer) GetLogContent(filename string) ([]LogEntry, error) {
-	path := filepath.Join(lv.config.LogDir, filename)
-	file, err := os.Open(path)
+// 文件列表排序方式
+const (
+	SortByName    = "name"
+	SortByModTime = "mtime"
+	SortBySize    = "size"
+)
+
+// LogFile 日志文件信息
+type LogFile struct {
+	Name             string         `json:"name"`
+	Size             int64          `json:"size"`                  // 文件大小
+	ModTime          time.Time      `json:"modTime"`               // 修改时间
+	Compressed       bool           `json:"compressed"`            // 是否为压缩文件
+	Compression      string         `json:"compression,omitempty"` // 压缩格式：gzip/zstd
+	UncompressedSize int64          `json:"uncompressedSize"`      // 解压后大小，未知时为 -1
+	Entries          *int           `json:"entries,omitempty"`     // 日志条数，仅在 ListOptions.Stats 时统计
+	Levels           map[string]int `json:"levels,omitempty"`      // 各级别条数，仅在 ListOptions.Stats 时统计
+}
+
+// ListOptions 文件列表选项
+type ListOptions struct {
+	Sort      string // 排序字段：name（默认）、mtime、size
+	Desc      bool   // 是否倒序
+	Stats     bool   // 是否统计日志条数，结果按文件大小和修改时间缓存
+	Recursive bool   // 是否列出子目录中的文件，文件名为相对日志目录的路径
+	MaxDepth  int    // 递归时最多进入的子目录层数，0 表示不限制
+	Name      string // 只列出该文件，用于单独统计一个文件，为空时列出全部
+}
+
+// LogTree 日志目录树节点
+type LogTree struct {
+	Name     string     `json:"name"`
+	Path     string     `json:"path"` // 相对日志目录的路径，根节点为空
+	Dir      bool       `json:"dir"`
+	File     *LogFile   `json:"file,omitempty"`
+	Children []*LogTree `json:"children,omitempty"`
+}
+
+// ListLogFiles 获取日志文件信息列表，只包含当前用户可查看的文件
+func (s *Source) ListLogFiles(opts ListOptions) ([]LogFile, error) {
+	if err := s.authorize(PermView, ""); err != nil {
+		return nil, err
+	}
+	files := []LogFile{}
+	err := s.walkLogFiles(opts, func(name string, info fs.FileInfo) error {
+		if !s.Can(PermView, name) {
+			return nil
+		}
+		file := LogFile{
+			Name:        name,
+			Size:        info.Size(),
+			ModTime:     info.ModTime(),
+			Compression: compressionOf(name),
+		}
+		file.Compressed = file.Compression != ""
+		file.UncompressedSize = file.Size
+		if file.Compressed && s.memory == nil {
+			file.UncompressedSize = s.uncompressedSize(name, file.Compression, file.Size)
+		}
+		if opts.Stats {
+			if err := s.fileStats(&file); err != nil {
+				return err
+			}
+		}
+		files = append(files, file)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
-	var logs []LogEntry
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		var log LogEntry
-		if err := json.Unmarshal(scanner.Bytes(), &log); err == nil {
-			logs = append(logs, log)
+	sortLogFiles(files, opts)
+	return files, nil
+}
+
+// walkLogFiles 遍历日志目录中的文件，name 为使用 "/" 分隔的相对路径，内存日志源只有一个虚拟文件
+func (s *Source) walkLogFiles(opts ListOptions, fn func(name string, info fs.FileInfo) error) error {
+	if s.memory != nil {
+		if opts.Name != "" && opts.Name != MemoryFileName {
+			return nil
+		}
+		return fn(MemoryFileName, s.memory.stat())
+	}
+	root := s.config.Dir
+	var dir *os.Root // 检查符号链接时打开
+	defer func() {
+		if dir != nil {
+			dir.Close()
+		}
+	}()
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil // 子目录不可读时跳过
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if d.IsDir() {
+			if path == root {
+				return nil
+			}
+			if !opts.Recursive || (opts.MaxDepth > 0 && strings.Count(name, "/") >= opts.MaxDepth) || s.excluded(name) {
+				return filepath.SkipDir
+			}
+			if opts.Name != "" && !strings.HasPrefix(opts.Name, name+"/") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if (opts.Name != "" && name != opts.Name) || s.hidden(name) {
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			// 与 openFile 的规则相同：列出指向日志目录内普通文件的符号链接，忽略指向目录之外或非普通文件的链接
+			if dir == nil {
+				if dir, err = os.OpenRoot(root); err != nil {
+					return err
+				}
+			}
+			info, err := dir.Stat(filepath.FromSlash(name))
+			if regularFile(name, info, err) != nil {
+				return nil
+			}
+			return fn(name, info)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil // 文件在读取目录后被删除
+		}
+		return fn(name, info)
+	})
+}
+
+// GetLogTree 以目录树形式返回日志文件，目录在前，同级文件按选项排序
+func (s *Source) GetLogTree(opts ListOptions) (*LogTree, error) {
+	opts.Recursive = true
+	files, err := s.ListLogFiles(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &LogTree{Name: filepath.Base(s.config.Dir), Dir: true}
+	if s.memory != nil {
+		root.Name = s.config.Name
+	}
+	dirs := map[string]*LogTree{"": root}
+	var dirOf func(p string) *LogTree
+	dirOf = func(p string) *LogTree {
+		if node, ok := dirs[p]; ok {
+			return node
 		}
+		parent := dirOf(parentDir(p))
+		node := &LogTree{Name: path.Base(p), Path: p, Dir: true}
+		parent.Children = append(parent.Children, node)
+		dirs[p] = node
+		return node
+	}
+	for i := range files {
+		file := &files[i]
+		parent := dirOf(parentDir(file.Name))
+		parent.Children = append(parent.Children, &LogTree{Name: path.Base(file.Name), Path: file.Name, File: file})
 	}
-	return logs, scanner.Err()
+
+	for _, node := range dirs {
+		sort.SliceStable(node.Children, func(i, j int) bool {
+			a, b := node.Children[i], node.Children[j]
+			if a.Dir != b.Dir {
+				return a.Dir
+			}
+			if a.Dir {
+				return a.Name < b.Name
+			}
+			return false
+		})
+	}
+	return root, nil
 }
 
-// DeleteAllLogs 删除所有日志文件
-func (lv *LogViewer) DeleteAllLogs() error {
-	if !lv.config.DevMode || !lv.config.EnableDelete {
-		return fmt.Errorf("delete operation is disabled")
+// parentDir 返回相对路径的上级目录，顶层为空字符串
+func parentDir(p string) string {
+	if dir := path.Dir(p); dir != "." {
+		return dir
 	}
+	return ""
+}
 
-	entries, err := os.ReadDir(lv.config.LogDir)
+// fileStats 统计文件的日志条数，复用分页索引缓存
+func (s *Source) fileStats(info *LogFile) error {
+	file, path, err := s.open(PermView, info.Name)
 	if err != nil {
 		return err
 	}
+	defer file.Close()
 
-	for _, entry := range entries {
-		path := filepath.Join(lv.config.LogDir, entry.Name())
-		if err := os.Remove(path); err != nil {
+	idx, err := s.lv.fileIndex(path, file, s.lv.fileParser(info.Name, file))
+	if err != nil {
+		return err
+	}
+	info.Entries = &idx.total
+	info.Levels = idx.levelCounts()
+	if file.compression != "" {
+		info.UncompressedSize = idx.length // 压缩文件读完后的准确大小
+	}
+	return nil
+}
+
+// sortLogFiles 按选项排序，相同时按名称排序
+func sortLogFiles(files []LogFile, opts ListOptions) {
+	sort.SliceStable(files, func(i, j int) bool {
+		a, b := files[i], files[j]
+		if opts.Desc {
+			a, b = b, a
+		}
+		switch opts.Sort {
+		case SortByModTime:
+			if !a.ModTime.Equal(b.ModTime) {
+				return a.ModTime.Before(b.ModTime)
+			}
+		case SortBySize:
+			if a.Size != b.Size {
+				return a.Size < b.Size
+			}
+		}
+		return a.Name < b.Name
+	})
+}
+
+// GetLogContent 获取日志内容，可传入过滤条件在读取时筛选
+func (s *Source) GetLogContent(filename string, filters ...*Filter) ([]LogEntry, error) {
+	file, _, err := s.open(PermView, filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	parser := s.lv.fileParser(filename, file)
+	r, err := file.reader(0)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var logs []LogEntry
+	err = readLines(r, 0, func(line []byte, _, _ int64) bool {
+		if log, ok := parser.Parse(line); ok && matchAll(filters, &log) {
+			logs = append(logs, log)
+		}
+		return true
+	})
+	return logs, err
+}
+
+// DeleteAllLogs 删除所有日志文件，角色限定了文件范围时只删除范围内的文件
+func (s *Source) DeleteAllLogs() error {
+	if err := s.authorize(PermDelete, ""); err != nil {
+		return err
+	}
+
+	// 只删除文件，保留子目录结构
+	return s.walkLogFiles(ListOptions{Recursive: true}, func(name string, _ fs.FileInfo) error {
+		if !s.Can(PermDelete, name) {
+			return nil
+		}
+		return s.removeFile(PermDelete, name)
+	})
+}
+
+// ClearFileContent 清空文件内容，内存日志源清空缓冲区
+func (s *Source) ClearFileContent(filename string) error {
+	if s.memory != nil {
+		if _, err := s.checkName(PermClear, filename); err != nil {
 			return err
 		}
+		s.memory.Reset()
+		return nil
 	}
-	return nil
+	file, _, err := s.openFile(PermClear, filename, os.O_WRONLY)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return file.Truncate(0)
 }
 
-// ClearFileContent 清空文件内容
-func (lv *LogViewer) ClearFileContent(filename string) error {
-	if !lv.config.DevMode || !lv.config.EnableClear {
-		return fmt.Errorf("clear operation is disabled")
+// ExportContent 读取导出的文件内容，压缩文件返回解压后的内容
+func (s *Source) ExportContent(filename string) ([]byte, error) {
+	file, _, err := s.open(PermExport, filename)
+	if err != nil {
+		return nil, err
 	}
-	path := filepath.Join(lv.config.LogDir, filename)
-	return os.WriteFile(path, []byte{}, 0644)
+	defer file.Close()
+	return file.readAll()
 }
 
-func (lv *LogViewer) ExportFile(filename string) error {
-	path := filepath.Join(lv.config.LogDir, filename)
-	return os.Remove(path)
+// ExportFile 删除文件，需要删除权限
+func (s *Source) ExportFile(filename string) error {
+	return s.removeFile(PermDelete, filename)
 }
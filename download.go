@@ -0,0 +1,131 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/18 00:12:45
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/18 00:12:45
+ * Description: 以附件形式流式下载原始日志文件，支持 Range、条件请求及 gzip 压缩传输
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// 导出格式
+const (
+	ExportJSON = "json" // 默认，文件内容包装在 JSON 响应中
+	ExportText = "text" // 以附件形式下载原始文件
+)
+
+// downloadFile 以附件形式流式返回原始文件，压缩文件按原样下载。
+// Range、If-Modified-Since、If-None-Match 由 http.ServeContent 处理；
+// 客户端接受 gzip 且不是 Range 请求时，未压缩的文件以 gzip 编码传输
+func (lv *LogViewer) downloadFile(w http.ResponseWriter, r *http.Request, src *Source, name string) {
+	file, _, err := src.open(PermExport, name)
+	lv.audit(r, PermExport, src, name, err)
+	if errors.Is(err, fs.ErrNotExist) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", downloadContentType(file.compression))
+	header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(name)}))
+	header.Set("Cache-Control", "private, no-cache")
+	header.Add("Vary", "Accept-Encoding")
+	etag := fmt.Sprintf("%x-%x", info.ModTime().UnixNano(), info.Size())
+
+	if file.compression == "" && (r.Method == http.MethodGet || r.Method == http.MethodHead) && r.Header.Get("Range") == "" && acceptsGzip(r) {
+		// gzip 编码的内容与原文件不同，使用不同的 ETag，HEAD 与 GET 返回相同的响应头
+		header.Set("ETag", `"`+etag+`-gzip"`)
+		gw := &gzipResponseWriter{ResponseWriter: w, head: r.Method == http.MethodHead}
+		defer gw.Close()
+		http.ServeContent(gw, r, "", info.ModTime(), file)
+		return
+	}
+	header.Set("ETag", `"`+etag+`"`)
+	http.ServeContent(w, r, "", info.ModTime(), file)
+}
+
+// downloadContentType 下载文件的 Content-Type
+func downloadContentType(compression string) string {
+	switch compression {
+	case CompressionGzip:
+		return "application/gzip"
+	case CompressionZstd:
+		return "application/zstd"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// acceptsGzip 判断客户端是否接受 gzip 编码，q 值不大于 0 或无法解析时视为拒绝
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(param, "=")
+			if strings.EqualFold(strings.TrimSpace(key), "q") {
+				q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				return err == nil && q > 0
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter 只对 200 响应做 gzip 编码，304 及错误响应原样返回，HEAD 只设置响应头
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz   *gzip.Writer
+	head bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	if code == http.StatusOK {
+		w.Header().Del("Content-Length")
+		w.Header().Set("Content-Encoding", "gzip")
+		if !w.head {
+			w.gz = gzip.NewWriter(w.ResponseWriter)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Close 写入 gzip 结尾
+func (w *gzipResponseWriter) Close() error {
+	if w.gz == nil {
+		return nil
+	}
+	return w.gz.Close()
+}
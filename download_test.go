@@ -0,0 +1,121 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/18 00:31:19
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/18 00:31:19
+ * Description: 原始文件下载测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportFileHandler_Download(t *testing.T) {
+	tempDir := t.TempDir()
+	content := strings.Repeat(`{"level":"INFO","msg":"hello"}`+"\n", 100)
+	appendLine(t, filepath.Join(tempDir, "app.log"), content)
+	writeCompressed(t, filepath.Join(tempDir, "old.log.gz"), 3)
+	modTime := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	os.Chtimes(filepath.Join(tempDir, "app.log"), modTime, modTime)
+
+	lv := New(&Config{LogDir: tempDir, EnableExport: true})
+	handler := lv.Handler("/log")
+	do := func(path string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// 完整下载
+	w := do("/log/exportFile?name=app.log&format=text", nil)
+	if w.Code != http.StatusOK || w.Body.String() != content {
+		t.Fatalf("Unexpected download: %d %d bytes", w.Code, w.Body.Len())
+	}
+	if got := w.Header().Get("Content-Disposition"); got != `attachment; filename=app.log` {
+		t.Errorf("Unexpected Content-Disposition: %s", got)
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" || w.Header().Get("Last-Modified") != modTime.Format(http.TimeFormat) {
+		t.Errorf("Expected ETag and Last-Modified, got %v", w.Header())
+	}
+
+	// Range 请求
+	w = do("/log/exportFile?name=app.log&format=text", map[string]string{"Range": "bytes=0-9", "Accept-Encoding": "gzip"})
+	if w.Code != http.StatusPartialContent || w.Body.String() != content[:10] || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Unexpected range response: %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+	if got := w.Header().Get("Content-Range"); got != "bytes 0-9/"+strconv.Itoa(len(content)) {
+		t.Errorf("Unexpected Content-Range: %s", got)
+	}
+
+	// 条件请求
+	if w := do("/log/exportFile?name=app.log&format=text", map[string]string{"If-None-Match": etag}); w.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for If-None-Match, got %d", w.Code)
+	}
+	if w := do("/log/exportFile?name=app.log&format=text", map[string]string{"If-Modified-Since": modTime.Format(http.TimeFormat)}); w.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for If-Modified-Since, got %d", w.Code)
+	}
+
+	// gzip 编码
+	w = do("/log/exportFile?name=app.log&format=text", map[string]string{"Accept-Encoding": "gzip, deflate"})
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("ETag") == etag {
+		t.Fatalf("Expected gzip encoding with its own ETag, got %v", w.Header())
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded, _ := io.ReadAll(zr); string(decoded) != content {
+		t.Errorf("Unexpected gzip content: %d bytes", len(decoded))
+	}
+	gzipETag := w.Header().Get("ETag")
+	for _, accept := range []string{"gzip;q=0", "gzip; q=0.0", "gzip;q=0.00", "gzip;q=abc"} {
+		if w := do("/log/exportFile?name=app.log&format=text", map[string]string{"Accept-Encoding": accept}); w.Header().Get("Content-Encoding") != "" {
+			t.Errorf("%s should not be encoded", accept)
+		}
+	}
+	if w := do("/log/exportFile?name=app.log&format=text", map[string]string{"Accept-Encoding": "gzip;q=0.5"}); w.Header().Get("Content-Encoding") != "gzip" {
+		t.Error("gzip;q=0.5 should be encoded")
+	}
+
+	// HEAD 与 GET 返回相同的编码及 ETag，不返回响应体
+	req := httptest.NewRequest("HEAD", "/log/exportFile?name=app.log&format=text", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("ETag") != gzipETag || w.Body.Len() != 0 {
+		t.Errorf("Unexpected HEAD response: %v %d bytes", w.Header(), w.Body.Len())
+	}
+
+	// 压缩文件按原样下载，不再编码
+	w = do("/log/exportFile?name=old.log.gz&format=text", map[string]string{"Accept-Encoding": "gzip"})
+	if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "" || w.Header().Get("Content-Type") != "application/gzip" {
+		t.Errorf("Unexpected response for compressed file: %d %v", w.Code, w.Header())
+	}
+
+	if w := do("/log/exportFile?name=missing.log&format=text", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", w.Code)
+	}
+	if w := do("/log/exportFile?name=app.log&format=pdf", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown format, got %d", w.Code)
+	}
+	// 默认仍返回 JSON
+	if w := do("/log/exportFile?name=app.log", nil); !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		t.Errorf("Expected JSON response by default, got %v", w.Header())
+	}
+}
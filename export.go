@@ -0,0 +1,342 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/18 01:02:27
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/18 01:02:27
+ * Description: 按过滤条件导出日志为 NDJSON、CSV 或 XLSX，边读边写，不缓存导出内容
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"math"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// 过滤导出格式
+const (
+	ExportNDJSON = "ndjson"
+	ExportCSV    = "csv"
+	ExportXLSX   = "xlsx"
+)
+
+const (
+	xlsxMaxRows       = 1 << 20 // XLSX 单个工作表的最大行数，超出的日志不再导出
+	xlsxMaxCellLength = 32767   // XLSX 单元格最大字符数
+)
+
+// errStopExport 达到 XLSX 行数上限时停止读取
+var errStopExport = errors.New("export row limit reached")
+
+// ExportEntries 按过滤条件依次读取文件中的日志，需要导出权限，fn 返回错误时停止
+func (s *Source) ExportEntries(filename string, filter *Filter, fn func(LogEntry) error) error {
+	file, _, err := s.open(PermExport, filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	parser := s.lv.fileParser(filename, file)
+	r, err := file.reader(0)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var fnErr error
+	err = readLines(r, 0, func(line []byte, _, _ int64) bool {
+		if entry, ok := parser.Parse(line); ok && filter.Match(&entry) {
+			fnErr = fn(entry)
+		}
+		return fnErr == nil
+	})
+	if err != nil {
+		return err
+	}
+	return fnErr
+}
+
+// exportColumns 第一遍读取，收集过滤后日志的全部属性键作为表格列，分组属性展开为 "group.key"
+func (s *Source) exportColumns(filename string, filter *Filter) ([]string, error) {
+	keys := map[string]bool{}
+	err := s.ExportEntries(filename, filter, func(entry LogEntry) error {
+		flattenAttrs("", entry.Attrs, func(key string, _ interface{}) {
+			keys[key] = true
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]string, 0, len(keys))
+	for key := range keys {
+		columns = append(columns, key)
+	}
+	sort.Strings(columns)
+	return append([]string{"time", "level", "msg"}, columns...), nil
+}
+
+// flattenAttrs 展开嵌套的分组属性
+func flattenAttrs(prefix string, attrs map[string]interface{}, fn func(key string, value interface{})) {
+	for key, value := range attrs {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if group, ok := value.(map[string]interface{}); ok {
+			flattenAttrs(key, group, fn)
+			continue
+		}
+		fn(key, value)
+	}
+}
+
+// exportRow 按列取出日志的各字段，数字保持为 json.Number
+func exportRow(columns []string, entry LogEntry) []interface{} {
+	values := map[string]interface{}{}
+	flattenAttrs("", entry.Attrs, func(key string, value interface{}) {
+		values[key] = value
+	})
+	row := make([]interface{}, len(columns))
+	row[0], row[1], row[2] = entry.Time, entry.Level, entry.Msg
+	for i := 3; i < len(columns); i++ {
+		row[i] = values[columns[i]]
+	}
+	return row
+}
+
+// cellText 将属性值转为单元格文本，数组等复杂值使用 JSON
+func cellText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// exportFiltered 以附件形式流式返回过滤后的日志，CSV 和 XLSX 需要先读取一遍文件确定列
+func (lv *LogViewer) exportFiltered(w http.ResponseWriter, r *http.Request, src *Source, name, format string, filter *Filter) {
+	var columns []string
+	_, err := src.resolvePath(PermExport, name)
+	if err == nil && format != ExportNDJSON {
+		columns, err = src.exportColumns(name, filter)
+	}
+	lv.audit(r, PermExport, src, name, err)
+	if errors.Is(err, fs.ErrNotExist) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+
+	filename := strings.TrimSuffix(path.Base(name), path.Ext(name)) + "." + format
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.Header().Set("Cache-Control", "no-store")
+	switch format {
+	case ExportNDJSON:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		err = writeNDJSON(w, src, name, filter)
+	case ExportCSV:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		err = writeCSV(w, src, name, filter, columns)
+	case ExportXLSX:
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		err = writeXLSX(w, src, name, filter, columns)
+	}
+	// 响应已开始发送，无法再返回错误状态
+	if err != nil {
+		slog.Error("goslogviewer: export interrupted", "file", name, "format", format, "err", err)
+	}
+}
+
+// writeNDJSON 每行一条 JSON 格式的日志
+func writeNDJSON(w io.Writer, src *Source, name string, filter *Filter) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	if err := src.ExportEntries(name, filter, func(entry LogEntry) error {
+		return enc.Encode(entry)
+	}); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// writeCSV 第一行为列名，属性展开为列
+func writeCSV(w io.Writer, src *Source, name string, filter *Filter, columns []string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	record := make([]string, len(columns))
+	err := src.ExportEntries(name, filter, func(entry LogEntry) error {
+		for i, value := range exportRow(columns, entry) {
+			record[i] = csvSafe(cellText(value))
+		}
+		return cw.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvSafe 以 "=", "+", "-", "@" 开头的文本在电子表格中会被当作公式执行，加上单引号前缀，数字除外
+func csvSafe(s string) string {
+	if s == "" || !strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return s
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return s
+	}
+	return "'" + s
+}
+
+// writeXLSX 生成只有一个工作表的 XLSX，单元格使用内联字符串，工作表边读边写入 zip
+func writeXLSX(w io.Writer, src *Source, name string, filter *Filter, columns []string) error {
+	zw := zip.NewWriter(w)
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="logs" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	sheet := bufio.NewWriter(f)
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	rows := 0
+	writeRow := func(values []interface{}) {
+		rows++
+		fmt.Fprintf(sheet, `<row r="%d">`, rows)
+		for i, value := range values {
+			ref := xlsxColumn(i) + strconv.Itoa(rows)
+			if n, ok := xlsxNumber(value); ok {
+				fmt.Fprintf(sheet, `<c r="%s"><v>%s</v></c>`, ref, n)
+				continue
+			}
+			text := cellText(value)
+			if text == "" {
+				continue
+			}
+			fmt.Fprintf(sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+			xmlEscape(sheet, truncateRunes(text, xlsxMaxCellLength))
+			sheet.WriteString(`</t></is></c>`)
+		}
+		sheet.WriteString(`</row>`)
+	}
+
+	header := make([]interface{}, len(columns))
+	for i, column := range columns {
+		header[i] = column
+	}
+	writeRow(header)
+	err = src.ExportEntries(name, filter, func(entry LogEntry) error {
+		if rows >= xlsxMaxRows {
+			return errStopExport
+		}
+		writeRow(exportRow(columns, entry))
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopExport) {
+		return err
+	}
+
+	sheet.WriteString(`</sheetData></worksheet>`)
+	if err := sheet.Flush(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// xlsxNumber 返回可作为数字单元格的值，超出 Excel 精度（15 位）的数字（如 ID）按文本导出
+func xlsxNumber(value interface{}) (string, bool) {
+	n, ok := value.(json.Number)
+	if !ok {
+		return "", false
+	}
+	f, err := n.Float64()
+	if err != nil || math.Abs(f) >= 1e15 {
+		return "", false
+	}
+	return n.String(), true
+}
+
+// xlsxColumn 返回列序号（从 0 开始）对应的列名，如 0 为 A，26 为 AA
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// xmlEscape 转义 XML 文本，XML 不允许的控制字符替换为 U+FFFD
+func xmlEscape(w *bufio.Writer, s string) {
+	for _, r := range s {
+		switch {
+		case r == '<':
+			w.WriteString("&lt;")
+		case r == '>':
+			w.WriteString("&gt;")
+		case r == '&':
+			w.WriteString("&amp;")
+		case r == '\t' || r == '\n' || r == '\r' || (r >= 0x20 && r != utf8.RuneError && r != 0xFFFE && r != 0xFFFF):
+			w.WriteRune(r)
+		default:
+			w.WriteRune(utf8.RuneError)
+		}
+	}
+}
+
+// truncateRunes 截断超过 n 个字符的文本
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,124 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/18 01:26:40
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/18 01:26:40
+ * Description: 过滤导出测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// exportFiles 包含属性及分组的日志文件
+var exportFiles = map[string]string{"app.log": strings.Join([]string{
+	`{"time":"2026-10-01T14:00:00Z","level":"INFO","msg":"start","request_id":"r1"}`,
+	`{"time":"2026-10-01T14:05:00Z","level":"ERROR","msg":"=HYPERLINK(\"x\")","request_id":"r1","http":{"status":500,"path":"/a"}}`,
+	`{"time":"2026-10-01T14:10:00Z","level":"ERROR","msg":"db <down> & out","request_id":"r1","retry":true}`,
+	`{"time":"2026-10-01T14:20:00Z","level":"ERROR","msg":"other request","request_id":"r2"}`,
+	`{"time":"2026-10-01T14:40:00Z","level":"ERROR","msg":"too late","request_id":"r1"}`,
+}, "\n") + "\n"}
+
+func TestExportFileHandler_Filtered(t *testing.T) {
+	lv := newTestViewer(t, exportFiles, &Config{EnableExport: true})
+	handler := lv.Handler("/log")
+	filter := "&level=ERROR&attr.request_id=r1&from=2026-10-01T14:00:00Z&to=2026-10-01T14:30:00Z"
+	get := func(format string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/log/exportFile?name=app.log&format="+format+filter, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s export failed: %d %s", format, w.Code, w.Body.String())
+		}
+		return w
+	}
+
+	t.Run("ndjson", func(t *testing.T) {
+		w := get(ExportNDJSON)
+		if w.Header().Get("Content-Type") != "application/x-ndjson" || !strings.Contains(w.Header().Get("Content-Disposition"), "app.ndjson") {
+			t.Errorf("Unexpected headers: %v", w.Header())
+		}
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("Expected 2 entries, got %d: %s", len(lines), w.Body.String())
+		}
+		var entry LogEntry
+		if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil || entry.Msg != "db <down> & out" {
+			t.Errorf("Unexpected entry: %+v %v", entry, err)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		w := get(ExportCSV)
+		records, err := csv.NewReader(w.Body).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"time", "level", "msg", "http.path", "http.status", "request_id", "retry"}
+		if strings.Join(records[0], ",") != strings.Join(want, ",") {
+			t.Fatalf("Unexpected columns: %v", records[0])
+		}
+		if len(records) != 3 {
+			t.Fatalf("Expected 2 rows, got %v", records[1:])
+		}
+		// 公式被转义，分组属性展开为列
+		if records[1][2] != `'=HYPERLINK("x")` || records[1][3] != "/a" || records[1][4] != "500" || records[1][6] != "" {
+			t.Errorf("Unexpected row: %v", records[1])
+		}
+		if records[2][6] != "true" {
+			t.Errorf("Unexpected row: %v", records[2])
+		}
+	})
+
+	t.Run("xlsx", func(t *testing.T) {
+		body := get(ExportXLSX).Body.Bytes()
+		zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+		if err != nil {
+			t.Fatalf("Invalid xlsx: %v", err)
+		}
+		var sheet string
+		for _, f := range zr.File {
+			if f.Name == "xl/worksheets/sheet1.xml" {
+				rc, _ := f.Open()
+				data, _ := io.ReadAll(rc)
+				rc.Close()
+				sheet = string(data)
+			}
+		}
+		for _, want := range []string{
+			`<c r="E2"><v>500</v></c>`,
+			`<c r="C3" t="inlineStr"><is><t xml:space="preserve">db &lt;down&gt; &amp; out</t></is></c>`,
+			`<c r="G1" t="inlineStr"><is><t xml:space="preserve">retry</t></is></c>`,
+		} {
+			if !strings.Contains(sheet, want) {
+				t.Errorf("Expected sheet to contain %s", want)
+			}
+		}
+		if strings.Count(sheet, "<row ") != 3 {
+			t.Errorf("Expected 3 rows, got %d", strings.Count(sheet, "<row "))
+		}
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/log/exportFile?name=app.log&format=csv&level=NOPE", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid filter, got %d", w.Code)
+	}
+}
+
+func TestXLSXColumn(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 701: "ZZ", 702: "AAA"} {
+		if got := xlsxColumn(i); got != want {
+			t.Errorf("xlsxColumn(%d) = %s, want %s", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 15:28:44
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 15:28:44
+ * Description: 文件列表测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListLogFiles_Sort(t *testing.T) {
+	tempDir := t.TempDir()
+	lv := New(&Config{LogDir: tempDir})
+
+	// b.log 最大，c.log 最新
+	now := time.Now()
+	files := []struct {
+		name    string
+		size    int
+		modTime time.Time
+	}{
+		{"a.log", 10, now.Add(-2 * time.Hour)},
+		{"b.log", 30, now.Add(-3 * time.Hour)},
+		{"c.log", 20, now.Add(-1 * time.Hour)},
+	}
+	for _, f := range files {
+		path := filepath.Join(tempDir, f.name)
+		os.WriteFile(path, []byte(strings.Repeat("x", f.size)), 0644)
+		os.Chtimes(path, f.modTime, f.modTime)
+	}
+	os.Mkdir(filepath.Join(tempDir, "archive"), 0755)
+
+	tests := []struct {
+		opts ListOptions
+		want string
+	}{
+		{ListOptions{}, "a.log,b.log,c.log"},
+		{ListOptions{Desc: true}, "c.log,b.log,a.log"},
+		{ListOptions{Sort: SortByModTime, Desc: true}, "c.log,a.log,b.log"},
+		{ListOptions{Sort: SortBySize}, "a.log,c.log,b.log"},
+	}
+	for _, tt := range tests {
+		list, err := lv.ListLogFiles(tt.opts)
+		if err != nil {
+			t.Fatalf("ListLogFiles failed: %v", err)
+		}
+		var names []string
+		for _, f := range list {
+			names = append(names, f.Name)
+		}
+		if got := strings.Join(names, ","); got != tt.want {
+			t.Errorf("ListLogFiles(%+v) = %s, want %s", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestListLogFiles_Stats(t *testing.T) {
+	tempDir := t.TempDir()
+	lv := New(&Config{LogDir: tempDir})
+	path := filepath.Join(tempDir, "app.log")
+	appendLine(t, path, `{"level":"INFO","msg":"a"}`+"\n"+`{"level":"ERROR","msg":"b"}`+"\n"+`{"level":"warn","msg":"c"}`+"\n")
+
+	list, err := lv.ListLogFiles(ListOptions{})
+	if err != nil {
+		t.Fatalf("ListLogFiles failed: %v", err)
+	}
+	if list[0].Entries != nil || list[0].Levels != nil {
+		t.Error("Stats should only be computed on request")
+	}
+
+	list, err = lv.ListLogFiles(ListOptions{Stats: true})
+	if err != nil {
+		t.Fatalf("ListLogFiles failed: %v", err)
+	}
+	if *list[0].Entries != 3 || list[0].Levels["INFO"] != 1 || list[0].Levels["WARN"] != 1 || list[0].Levels["ERROR"] != 1 {
+		t.Errorf("Unexpected stats: %d %v", *list[0].Entries, list[0].Levels)
+	}
+
+	// 追加内容后增量更新，包括未写完的最后一行
+	appendLine(t, path, `{"level":"ERROR","msg":"d"}`+"\n"+`{"level":"ERROR","msg":"e"}`)
+	list, _ = lv.ListLogFiles(ListOptions{Stats: true})
+	if *list[0].Entries != 5 || list[0].Levels["ERROR"] != 3 {
+		t.Errorf("Unexpected stats after append: %d %v", *list[0].Entries, list[0].Levels)
+	}
+}
+
+func TestListLogFiles_Name(t *testing.T) {
+	tempDir := t.TempDir()
+	lv := New(&Config{LogDir: tempDir})
+	appendLine(t, filepath.Join(tempDir, "app.log"), `{"level":"INFO","msg":"a"}`+"\n")
+	os.MkdirAll(filepath.Join(tempDir, "sub"), 0755)
+	appendLine(t, filepath.Join(tempDir, "sub", "app.log"), `{"level":"ERROR","msg":"b"}`+"\n")
+
+	// 只统计指定的文件，其他文件不建立索引
+	list, err := lv.ListLogFiles(ListOptions{Name: "sub/app.log", Recursive: true, Stats: true})
+	if err != nil || len(list) != 1 || list[0].Name != "sub/app.log" || list[0].Levels["ERROR"] != 1 {
+		t.Fatalf("Unexpected list: %+v %v", list, err)
+	}
+	if lv.indexes.len() != 1 {
+		t.Errorf("Expected only the named file to be indexed, got %d indexes", lv.indexes.len())
+	}
+}
+
+func TestGetFilesHandler_Details(t *testing.T) {
+	tempDir := t.TempDir()
+	lv := New(&Config{LogDir: tempDir})
+	appendLine(t, filepath.Join(tempDir, "small.log"), `{"level":"INFO","msg":"a"}`+"\n")
+	appendLine(t, filepath.Join(tempDir, "big.log"), strings.Repeat(`{"level":"INFO","msg":"a"}`+"\n", 3))
+
+	req := httptest.NewRequest("GET", "/log/getLogFilesList?sort=size&order=desc&stats=1", nil)
+	w := httptest.NewRecorder()
+	lv.GetFilesHandler(w, req)
+
+	var response struct {
+		Files   []string  `json:"files"`
+		Details []LogFile `json:"details"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if strings.Join(response.Files, ",") != "big.log,small.log" {
+		t.Errorf("Unexpected order: %v", response.Files)
+	}
+	if d := response.Details[0]; d.Entries == nil || *d.Entries != 3 || d.ModTime.IsZero() || d.Compressed {
+		t.Errorf("Unexpected details: %+v", d)
+	}
+
+	req = httptest.NewRequest("GET", "/log/getLogFilesList?sort=color", nil)
+	w = httptest.NewRecorder()
+	lv.GetFilesHandler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status BadRequest for invalid sort, got %d", w.Code)
+	}
+}
@@ -0,0 +1,251 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 10:20:13
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 10:20:13
+ * Description: 日志过滤条件
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// 过滤参数中属性条件的前缀，如 attr.request_id=abc
+const attrParamPrefix = "attr."
+
+// 支持的时间格式，不带时区的按本地时间解析
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// Filter 日志过滤条件，各条件之间为“与”关系
+type Filter struct {
+	MinLevel *slog.Level       // 最低日志级别
+	From     time.Time         // 开始时间（包含），零值表示不限
+	To       time.Time         // 结束时间（不包含），零值表示不限
+	Contains string            // 消息包含的文本，不区分大小写
+	Pattern  *regexp.Regexp    // 消息匹配的正则表达式
+	Attrs    map[string]string // 属性等值条件，分组属性用 "." 连接，如 req.method
+	Query    *Query            // 查询表达式，见 ParseQuery
+	Terms    []string          // 消息中必须包含的词，按 messageTokens 分词后比较，有持久索引时使用倒排索引
+}
+
+// Empty 判断是否没有任何过滤条件
+func (f *Filter) Empty() bool {
+	return f == nil || (f.MinLevel == nil && f.From.IsZero() && f.To.IsZero() &&
+		f.Contains == "" && f.Pattern == nil && len(f.Attrs) == 0 && f.Query == nil && len(f.Terms) == 0)
+}
+
+// Match 判断日志是否满足过滤条件
+func (f *Filter) Match(entry *LogEntry) bool {
+	if f.Empty() {
+		return true
+	}
+
+	if f.MinLevel != nil {
+		level, err := ParseLevel(entry.Level)
+		if err != nil || level < *f.MinLevel {
+			return false
+		}
+	}
+
+	if !f.From.IsZero() || !f.To.IsZero() {
+		t, err := ParseTime(entry.Time)
+		if err != nil {
+			return false
+		}
+		if !f.From.IsZero() && t.Before(f.From) {
+			return false
+		}
+		if !f.To.IsZero() && !t.Before(f.To) {
+			return false
+		}
+	}
+
+	if f.Contains != "" && !strings.Contains(strings.ToLower(entry.Msg), strings.ToLower(f.Contains)) {
+		return false
+	}
+
+	if f.Pattern != nil && !f.Pattern.MatchString(entry.Msg) {
+		return false
+	}
+
+	for key, want := range f.Attrs {
+		v, ok := lookupAttr(entry.Attrs, key)
+		if !ok || fmt.Sprint(v) != want {
+			return false
+		}
+	}
+
+	if len(f.Terms) > 0 {
+		tokens := make(map[string]bool)
+		for _, token := range messageTokens(entry.Msg) {
+			tokens[token] = true
+		}
+		for _, term := range f.Terms {
+			for _, token := range messageTokens(term) {
+				if !tokens[token] {
+					return false
+				}
+			}
+		}
+	}
+	return f.Query.Match(entry)
+}
+
+// messageTokens 将消息分词：转为小写，按非字母数字字符切分，每个汉字单独作为一个词，结果去重
+func messageTokens(msg string) []string {
+	var (
+		tokens []string
+		seen   = make(map[string]bool)
+		word   strings.Builder
+	)
+	add := func(token string) {
+		if token != "" && !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+	for _, r := range strings.ToLower(msg) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			add(word.String())
+			word.Reset()
+			add(string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		default:
+			add(word.String())
+			word.Reset()
+		}
+	}
+	add(word.String())
+	return tokens
+}
+
+// matchAll 判断日志是否满足全部过滤条件
+func matchAll(filters []*Filter, entry *LogEntry) bool {
+	for _, f := range filters {
+		if !f.Match(entry) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseFilter 从请求参数解析过滤条件：
+// level、from、to、text、regex、attr.<key>=<value>、查询表达式 q（见 ParseQuery）以及空格分隔的词 terms
+func ParseFilter(values url.Values) (*Filter, error) {
+	f := &Filter{}
+
+	if s := values.Get("level"); s != "" {
+		level, err := ParseLevel(s)
+		if err != nil {
+			return nil, err
+		}
+		f.MinLevel = &level
+	}
+
+	var err error
+	if s := values.Get("from"); s != "" {
+		if f.From, err = ParseTime(s); err != nil {
+			return nil, err
+		}
+	}
+	if s := values.Get("to"); s != "" {
+		if f.To, err = ParseTime(s); err != nil {
+			return nil, err
+		}
+	}
+
+	f.Contains = values.Get("text")
+	if s := values.Get("regex"); s != "" {
+		if f.Pattern, err = regexp.Compile(s); err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+	}
+
+	if s := values.Get("q"); strings.TrimSpace(s) != "" {
+		if f.Query, err = ParseQuery(s); err != nil {
+			return nil, err
+		}
+	}
+
+	f.Terms = strings.Fields(values.Get("terms"))
+
+	for key, vals := range values {
+		if name := strings.TrimPrefix(key, attrParamPrefix); name != key && name != "" && len(vals) > 0 {
+			if f.Attrs == nil {
+				f.Attrs = make(map[string]string)
+			}
+			f.Attrs[name] = vals[0]
+		}
+	}
+	return f, nil
+}
+
+// ParseLevel 解析日志级别，支持 DEBUG/INFO/WARN/ERROR、slog 的偏移写法（如 INFO+2）以及数字级别
+func ParseLevel(s string) (slog.Level, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil {
+		return slog.Level(n), nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("invalid level: %q", s)
+	}
+	return level, nil
+}
+
+// levelName 返回统一格式的级别名称，如 "warn" 转为 "WARN"，无法识别时原样返回
+func levelName(s string) string {
+	if s == "" {
+		return "UNKNOWN"
+	}
+	level, err := ParseLevel(s)
+	if err != nil {
+		return s
+	}
+	return level.String()
+}
+
+// ParseTime 解析日志时间
+func ParseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %q", s)
+}
+
+// lookupAttr 查找属性值，优先匹配完整键名，再按 "." 逐级查找分组
+func lookupAttr(attrs map[string]interface{}, key string) (interface{}, bool) {
+	if v, ok := attrs[key]; ok {
+		return v, true
+	}
+	group, rest, found := strings.Cut(key, ".")
+	if !found {
+		return nil, false
+	}
+	sub, ok := attrs[group].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return lookupAttr(sub, rest)
+}
@@ -0,0 +1,129 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 10:52:31
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 10:52:31
+ * Description: 过滤条件测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const filterTestLog = `{"time":"2023-01-01T10:00:00Z","level":"DEBUG","msg":"cache miss","key":"user:1"}
+{"time":"2023-01-01T10:05:00Z","level":"INFO","msg":"request done","request_id":"r1","req":{"method":"GET"}}
+{"time":"2023-01-01T10:10:00Z","level":"WARN","msg":"slow request","request_id":"r2","duration_ms":730}
+{"time":"2023-01-01T10:15:00Z","level":"ERROR","msg":"Request Timeout","request_id":"r2"}
+{"time":"2023-01-01T10:20:00Z","level":"ERROR+4","msg":"panic recovered","request_id":"r3"}
+`
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		input string
+		want  slog.Level
+	}{
+		{"DEBUG", slog.LevelDebug},
+		{"info", slog.LevelInfo},
+		{"WARN", slog.LevelWarn},
+		{"ERROR+4", slog.LevelError + 4},
+		{"INFO-2", slog.LevelInfo - 2},
+		{"12", slog.Level(12)},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", tt.input, got, err, tt.want)
+		}
+	}
+	if _, err := ParseLevel("LOUD"); err == nil {
+		t.Error("Expected error for unknown level")
+	}
+}
+
+func TestGetLogContent_Filter(t *testing.T) {
+	tempDir := t.TempDir()
+	lv := New(&Config{LogDir: tempDir})
+	if err := os.WriteFile(filepath.Join(tempDir, "app.log"), []byte(filterTestLog), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"Min level", "level=WARN", []string{"slow request", "Request Timeout", "panic recovered"}},
+		{"Custom level", "level=ERROR%2B1", []string{"panic recovered"}},
+		{"Time range", "from=2023-01-01T10:05:00Z&to=2023-01-01T10:15:00Z", []string{"request done", "slow request"}},
+		{"Text ignores case", "text=request", []string{"request done", "slow request", "Request Timeout"}},
+		{"Regex", "regex=^(cache|panic)", []string{"cache miss", "panic recovered"}},
+		{"Attribute", "attr.request_id=r2", []string{"slow request", "Request Timeout"}},
+		{"Nested attribute", "attr.req.method=GET", []string{"request done"}},
+		{"Numeric attribute", "attr.duration_ms=730", []string{"slow request"}},
+		{"Combined", "level=ERROR&attr.request_id=r2", []string{"Request Timeout"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			filter, err := ParseFilter(values)
+			if err != nil {
+				t.Fatalf("ParseFilter failed: %v", err)
+			}
+			entries, err := lv.GetLogContent("app.log", filter)
+			if err != nil {
+				t.Fatalf("GetLogContent failed: %v", err)
+			}
+			var got []string
+			for _, entry := range entries {
+				got = append(got, entry.Msg)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGetContentHandler_Filter(t *testing.T) {
+	tempDir := t.TempDir()
+	lv := New(&Config{LogDir: tempDir})
+	if err := os.WriteFile(filepath.Join(tempDir, "app.log"), []byte(filterTestLog), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/log/getFileContent?name=app.log&level=WARN&page=2&pageSize=2", nil)
+	w := httptest.NewRecorder()
+
+	lv.GetContentHandler(w, req)
+
+	var response struct {
+		Data  []LogEntry `json:"data"`
+		Total int        `json:"total"`
+	}
+	if err := json.NewDecoder(w.Result().Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Total != 3 || len(response.Data) != 1 || response.Data[0].Msg != "panic recovered" {
+		t.Errorf("Unexpected response: total %d, data %v", response.Total, response.Data)
+	}
+
+	for _, query := range []string{"level=LOUD", "from=yesterday", "regex=("} {
+		req = httptest.NewRequest("GET", "/log/getFileContent?name=app.log&"+query, nil)
+		w = httptest.NewRecorder()
+		lv.GetContentHandler(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status BadRequest for %s, got %d", query, w.Code)
+		}
+	}
+}
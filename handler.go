@@ -9,15 +9,34 @@
 package goslogviewer
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
 	"net/http"
+	"net/url"
 	"os"
-	"path/filepath"
-	"strings"
+	"strconv"
+	"sync"
+	"time"
 )
 
 type LogViewer struct {
 	config *Config
+
+	indexMu     sync.Mutex
+	indexes     *indexCache            // 分页偏移索引缓存
+	indexBuilds map[string]*indexBuild // 正在构建的分页索引，同一文件只构建一次
+
+	auditMu     sync.Mutex
+	auditPath   string       // 解析符号链接后的审计文件路径
+	auditFile   *os.File     // 审计文件，首次写入时打开
+	auditEvents []AuditEvent // 最近的审计事件
+
+	indexerMu  sync.Mutex
+	indexer    *Indexer // 持久索引，首次使用时打开
+	indexerErr error
 }
 
 func (lv *LogViewer) GetConfig() *Config {
@@ -29,7 +48,78 @@ func New(config *Config) *LogViewer {
 		config = DefaultConfig()
 	}
 
-	return &LogViewer{config: config}
+	lv := &LogViewer{
+		config:      config,
+		indexes:     newIndexCache(config.IndexCacheSize),
+		indexBuilds: make(map[string]*indexBuild),
+	}
+	if config.AuditFile != "" {
+		lv.auditPath, _ = realPath(config.AuditFile)
+	}
+	return lv
+}
+
+// NewWithError 创建 LogViewer 并检查配置，日志源重名时返回 ErrDuplicateSource
+func NewWithError(config *Config) (*LogViewer, error) {
+	lv := New(config)
+	if err := lv.checkMemorySource(); err != nil {
+		return nil, err
+	}
+	return lv, nil
+}
+
+// Close 关闭持久索引及审计文件
+func (lv *LogViewer) Close() error {
+	var errs []error
+	lv.indexerMu.Lock()
+	if lv.indexer != nil {
+		errs = append(errs, lv.indexer.Close())
+		lv.indexer = nil
+	}
+	lv.indexerMu.Unlock()
+
+	lv.auditMu.Lock()
+	if lv.auditFile != nil {
+		errs = append(errs, lv.auditFile.Close())
+		lv.auditFile = nil
+	}
+	lv.auditMu.Unlock()
+	return errors.Join(errs...)
+}
+
+// httpError 返回错误响应，参数错误返回 400，没有权限返回 403，其余返回 500
+func httpError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrInvalidPath) || errors.Is(err, ErrInvalidCursor) || errors.Is(err, ErrInvalidPage) || errors.Is(err, ErrUnknownSource) || errors.Is(err, ErrTooManyBuckets) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, ErrForbidden) || errors.Is(err, ErrDisabled) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// operationDenied 操作不被允许时返回响应：被 Enable* 开关禁用时返回 3001，角色不允许时返回 403
+func operationDenied(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrDisabled) {
+		respondJSON(w, map[string]interface{}{
+			"code":  3001,
+			"files": nil,
+			"msg":   err.Error(),
+		})
+		return
+	}
+	httpError(w, err)
+}
+
+// requestSource 返回请求参数 source 指定的日志源，未指定时为默认日志源，并绑定已认证的用户
+func (lv *LogViewer) requestSource(r *http.Request) (*Source, error) {
+	src, err := lv.Source(r.FormValue("source"))
+	if err != nil {
+		return nil, err
+	}
+	return src.As(IdentityFrom(r.Context())), nil
 }
 
 func respondJSON(w http.ResponseWriter, data interface{}) {
@@ -39,21 +129,83 @@ func respondJSON(w http.ResponseWriter, data interface{}) {
 	}
 }
 
-// GetFilesHandler 获取文件列表处理器
+// GetFilesHandler 获取文件列表处理器，sort 指定排序字段（name/mtime/size），
+// order=desc 倒序，stats=1 时统计各文件的日志条数，recursive=1 时包含子目录，
+// depth 限制递归层数，tree=1 时额外返回目录树，name 只返回该文件，用于单独统计一个文件
 func (lv *LogViewer) GetFilesHandler(w http.ResponseWriter, r *http.Request) {
-	files, err := lv.GetLogFiles()
+	query := r.URL.Query()
+	opts := ListOptions{
+		Sort:      query.Get("sort"),
+		Desc:      query.Get("order") == "desc",
+		Stats:     boolParam(query.Get("stats")),
+		Recursive: boolParam(query.Get("recursive")) || boolParam(query.Get("tree")),
+		Name:      query.Get("name"),
+	}
+	if depth := query.Get("depth"); depth != "" {
+		n, err := strconv.Atoi(depth)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid depth: "+depth, http.StatusBadRequest)
+			return
+		}
+		opts.MaxDepth = n
+	}
+	switch opts.Sort {
+	case "", SortByName, SortByModTime, SortBySize:
+	default:
+		http.Error(w, "invalid sort: "+opts.Sort, http.StatusBadRequest)
+		return
+	}
+
+	src, err := lv.requestSource(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		httpError(w, err)
 		return
 	}
-	respondJSON(w, map[string]interface{}{
-		"code":  200,
-		"files": files,
-		"msg":   "success",
-	})
+	details, err := src.ListLogFiles(opts)
+	if err != nil {
+		lv.auditDenied(r, PermView, src, "", err)
+		httpError(w, err)
+		return
+	}
+	files := make([]string, 0, len(details))
+	for _, file := range details {
+		files = append(files, file.Name)
+	}
+	resp := map[string]interface{}{
+		"code":    200,
+		"files":   files,
+		"details": details,
+		"msg":     "success",
+	}
+	if boolParam(query.Get("tree")) {
+		tree, err := src.GetLogTree(opts)
+		if err != nil {
+			httpError(w, err)
+			return
+		}
+		resp["tree"] = tree
+	}
+	respondJSON(w, resp)
+}
+
+func boolParam(s string) bool {
+	return s == "1" || s == "true"
+}
+
+// pageParam 解析页码或每页条数，未指定时返回 0，不是正整数时返回 ErrInvalidPage
+func pageParam(query url.Values, key string) (int, error) {
+	v := query.Get(key)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%w: %s=%q", ErrInvalidPage, key, v)
+	}
+	return n, nil
 }
 
-// GetContentHandler 获取日志内容处理器
+// GetContentHandler 获取日志内容处理器，支持 page/pageSize 分页或 cursor 游标，过滤参数见 ParseFilter
 func (lv *LogViewer) GetContentHandler(w http.ResponseWriter, r *http.Request) {
 	filename := r.URL.Query().Get("name")
 	if filename == "" {
@@ -61,27 +213,147 @@ func (lv *LogViewer) GetContentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	logs, err := lv.GetLogContent(filename)
+	query := r.URL.Query()
+	filter, err := ParseFilter(query)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	src, err := lv.requestSource(r)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	page, err := pageParam(query, "page")
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	pageSize, err := pageParam(query, "pageSize")
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	result, err := src.GetLogPage(filename, PageQuery{
+		Page:     page,
+		PageSize: pageSize,
+		Cursor:   query.Get("cursor"),
+		Filter:   filter,
+	})
+	if err != nil {
+		lv.auditDenied(r, PermView, src, filename, err)
+		httpError(w, err)
 		return
 	}
 
 	respondJSON(w, map[string]interface{}{
-		"code": 200,
-		"data": logs,
-		"msg":  "success",
+		"code":     200,
+		"data":     result.Entries,
+		"total":    result.Total,
+		"page":     result.Page,
+		"pageSize": result.PageSize,
+		"next":     result.NextCursor,
+		"msg":      "success",
+	})
+}
+
+// TailHandler 以 Server-Sent Events 推送文件新增的日志，支持与内容接口相同的过滤参数
+func (lv *LogViewer) TailHandler(w http.ResponseWriter, r *http.Request) {
+	filename := r.URL.Query().Get("name")
+	if filename == "" {
+		http.Error(w, "filename is required", http.StatusBadRequest)
+		return
+	}
+	filter, err := ParseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	src, err := lv.requestSource(r)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	if _, err := src.resolvePath(PermTail, filename); err != nil {
+		lv.auditDenied(r, PermTail, src, filename, err)
+		httpError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	// HEAD 只返回响应头，跟踪不会结束，处理器不返回时同一连接上的后续请求会被阻塞
+	if r.Method == http.MethodHead {
+		return
+	}
+	// 先写入一行注释，部分代理和服务器在收到响应体之前不会发出响应头
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	// 定时发送注释行，避免代理因空闲断开连接。返回前等待发送协程退出，之后不再写入 w
+	var mu sync.Mutex
+	ctx, cancel := context.WithCancel(r.Context())
+	pingDone := make(chan struct{})
+	defer func() {
+		cancel()
+		<-pingDone
+	}()
+	go func() {
+		defer close(pingDone)
+		ticker := time.NewTicker(15 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				mu.Lock()
+				fmt.Fprint(w, ": ping\n\n")
+				flusher.Flush()
+				mu.Unlock()
+			}
+		}
+	}()
+
+	err = src.Tail(ctx, filename, filter, func(event TailEvent) error {
+		data, err := json.Marshal(event.Entry)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
 	})
+	if err != nil {
+		mu.Lock()
+		fmt.Fprintf(w, "event: error\ndata: %q\n\n", err.Error())
+		flusher.Flush()
+		mu.Unlock()
+	}
 }
 
 // ClearFileContentHandler 清空文件内容
 func (lv *LogViewer) ClearFileContentHandler(w http.ResponseWriter, r *http.Request) {
-	if !lv.config.DevMode || !lv.config.EnableClear {
-		respondJSON(w, map[string]interface{}{
-			"code":  3001,
-			"files": nil,
-			"msg":   "clear operation is disabled",
-		})
+	src, err := lv.requestSource(r)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	if err := src.authorize(PermClear, ""); err != nil {
+		lv.audit(r, PermClear, src, r.FormValue("name"), err)
+		operationDenied(w, err)
 		return
 	}
 	if err := r.ParseForm(); err != nil {
@@ -93,9 +365,11 @@ func (lv *LogViewer) ClearFileContentHandler(w http.ResponseWriter, r *http.Requ
 		http.Error(w, "filename is required", http.StatusBadRequest)
 		return
 	}
-	err := lv.ClearFileContent(filename)
+	err = src.ClearFileContent(filename)
+	lv.audit(r, PermClear, src, filename, err)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		httpError(w, err)
+		return
 	}
 	respondJSON(w, map[string]interface{}{
 		"code": 200,
@@ -105,17 +379,21 @@ func (lv *LogViewer) ClearFileContentHandler(w http.ResponseWriter, r *http.Requ
 }
 
 func (lv *LogViewer) DeleteAllFilesHandler(w http.ResponseWriter, r *http.Request) {
-	if !lv.config.DevMode || !lv.config.EnableDelete {
-		respondJSON(w, map[string]interface{}{
-			"code":  3001,
-			"files": nil,
-			"msg":   "delete operation is disabled",
-		})
+	src, err := lv.requestSource(r)
+	if err != nil {
+		httpError(w, err)
 		return
 	}
-	err := lv.DeleteAllLogs()
+	if err := src.authorize(PermDelete, ""); err != nil {
+		lv.audit(r, PermDelete, src, "", err)
+		operationDenied(w, err)
+		return
+	}
+	err = src.DeleteAllLogs()
+	lv.audit(r, PermDelete, src, "", err)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 	respondJSON(w, map[string]interface{}{
 		"code": 200,
@@ -124,14 +402,17 @@ func (lv *LogViewer) DeleteAllFilesHandler(w http.ResponseWriter, r *http.Reques
 	})
 }
 
-// 导出指定文件
+// ExportFileHandler 导出指定文件，默认返回包含文件内容的 JSON，format=text 时以附件形式流式下载原始文件，
+// format=ndjson/csv/xlsx 时按与内容接口相同的过滤参数导出日志
 func (lv *LogViewer) ExportFileHandler(w http.ResponseWriter, r *http.Request) {
-	if !lv.config.EnableExport {
-		respondJSON(w, map[string]interface{}{
-			"code":  3001,
-			"files": nil,
-			"msg":   "export operation is disabled",
-		})
+	src, err := lv.requestSource(r)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	if err := src.authorize(PermExport, ""); err != nil {
+		lv.audit(r, PermExport, src, r.URL.Query().Get("name"), err)
+		operationDenied(w, err)
 		return
 	}
 
@@ -145,8 +426,28 @@ func (lv *LogViewer) ExportFileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 文件名安全校验
-	if strings.Contains(fileName, "..") || strings.Contains(fileName, "/") || strings.Contains(fileName, "\\") {
+	switch format := r.URL.Query().Get("format"); format {
+	case "", ExportJSON:
+	case ExportText:
+		lv.downloadFile(w, r, src, fileName)
+		return
+	case ExportNDJSON, ExportCSV, ExportXLSX:
+		filter, err := ParseFilter(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		lv.exportFiltered(w, r, src, fileName, format, filter)
+		return
+	default:
+		http.Error(w, "invalid format: "+format, http.StatusBadRequest)
+		return
+	}
+
+	// JSON 格式返回文件的全部内容，文件名由 ExportContent 校验
+	content, err := src.ExportContent(fileName)
+	lv.audit(r, PermExport, src, fileName, err)
+	if errors.Is(err, ErrInvalidPath) {
 		respondJSON(w, map[string]interface{}{
 			"code":  3003,
 			"files": nil,
@@ -154,11 +455,14 @@ func (lv *LogViewer) ExportFileHandler(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	if errors.Is(err, ErrForbidden) {
+		httpError(w, err)
+		return
+	}
 
-	filePath := filepath.Join(lv.config.LogDir, fileName)
-	content, err := os.ReadFile(filePath)
+	// 被通配符规则隐藏的文件与不存在的文件同样处理
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			respondJSON(w, map[string]interface{}{
 				"code":  3004,
 				"files": nil,
@@ -179,3 +483,29 @@ func (lv *LogViewer) ExportFileHandler(w http.ResponseWriter, r *http.Request) {
 		"msg":  "success",
 	})
 }
+
+// GetSourcesHandler 获取当前用户可查看的日志源及各日志源可用的操作，enableAudit 表示能否查看审计日志
+func (lv *LogViewer) GetSourcesHandler(w http.ResponseWriter, r *http.Request) {
+	identity := IdentityFrom(r.Context())
+	sources := []map[string]interface{}{}
+	for _, src := range lv.Sources() {
+		src = src.As(identity)
+		if !src.Can(PermView, "") {
+			continue
+		}
+		sources = append(sources, map[string]interface{}{
+			"name":         src.config.Name,
+			"enableTail":   src.Can(PermTail, ""),
+			"enableDelete": src.Can(PermDelete, ""),
+			"enableClear":  src.Can(PermClear, ""),
+			"enableExport": src.Can(PermExport, ""),
+			"memory":       src.memory != nil,
+		})
+	}
+	respondJSON(w, map[string]interface{}{
+		"code":        200,
+		"data":        sources,
+		"enableAudit": lv.authorizeAudit(identity) == nil,
+		"msg":         "success",
+	})
+}
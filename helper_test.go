@@ -0,0 +1,60 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/18 10:12:45
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/18 10:12:45
+ * Description: 测试公共方法
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestViewer 在日志目录中创建 files 中的文件（键为相对路径，值为内容）并返回 LogViewer。
+// cfg 为 nil 时使用空配置，未指定 LogDir 时使用临时目录，测试结束时自动关闭
+func newTestViewer(t *testing.T, files map[string]string, cfg *Config) *LogViewer {
+	t.Helper()
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	if cfg.LogDir == "" {
+		cfg.LogDir = t.TempDir()
+	}
+	if err := os.MkdirAll(cfg.LogDir, 0755); err != nil {
+		t.Fatalf("Failed to create log dir: %v", err)
+	}
+	for name, content := range files {
+		path := filepath.Join(cfg.LogDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	lv := New(cfg)
+	t.Cleanup(func() { lv.Close() })
+	return lv
+}
+
+// infoFiles 每个文件包含一条以文件名为消息的 INFO 日志
+func infoFiles(names ...string) map[string]string {
+	files := make(map[string]string, len(names))
+	for _, name := range names {
+		files[name] = `{"level":"INFO","msg":"` + name + `"}` + "\n"
+	}
+	return files
+}
+
+// setModTime 修改日志目录中文件的修改时间
+func setModTime(t *testing.T, lv *LogViewer, name string, modTime time.Time) {
+	t.Helper()
+	if err := os.Chtimes(filepath.Join(lv.config.LogDir, filepath.FromSlash(name)), modTime, modTime); err != nil {
+		t.Fatalf("Failed to set modification time: %v", err)
+	}
+}
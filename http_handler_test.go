@@ -14,6 +14,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -101,7 +102,7 @@ func TestHttpGetContentHandler(t *testing.T) {
 		t.Fatalf("Expected 1 entry, got %d", len(response.Data))
 	}
 
-	if response.Data[0] != testEntry {
+	if !reflect.DeepEqual(response.Data[0], testEntry) {
 		t.Errorf("Entry mismatch: expected %v, got %v", testEntry, response.Data[0])
 	}
 }
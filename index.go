@@ -0,0 +1,700 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/18 06:15:08
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/18 06:15:08
+ * Description: 持久索引，按时间、级别、属性值及消息分词保存记录偏移，加速大量日志的搜索和分页
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// 索引文件中的 bucket
+var (
+	bucketMeta  = []byte("meta")  // 索引格式版本
+	bucketFiles = []byte("files") // 文件路径 → indexMeta
+	bucketData  = []byte("data")  // 每个文件一个子 bucket，包含以下倒排 bucket
+	bucketTime  = []byte("time")  // 时间|偏移
+	bucketLevel = []byte("level") // 级别|偏移
+	bucketAttr  = []byte("attr")  // 属性键\0值\0偏移
+	bucketToken = []byte("token") // 分词\0偏移
+)
+
+const (
+	indexVersion         = "1"
+	indexBatchSize       = 50000 // 每个写事务索引的记录数，大文件分多次提交，中断后可继续
+	fingerprintSize      = 4096  // 用于识别文件被替换的开头字节数
+	maxIndexValue        = 128   // 属性值超过该长度时不建立索引
+	defaultIndexInterval = time.Minute
+)
+
+// Indexer 日志文件的持久索引，保存在 Config.IndexFile（bbolt）中。
+// Update 增量索引全部日志源中的文件：追加的内容从上次的位置继续，文件被截断或替换（轮转）时重建该文件的索引，
+// 已删除文件的索引随之删除。搜索及带过滤条件的分页优先使用索引，只读取候选行，索引之后追加的内容仍顺序读取
+type Indexer struct {
+	lv    *LogViewer
+	db    *bolt.DB
+	attrs map[string]bool
+	mu    sync.Mutex // 同一时间只进行一次更新
+}
+
+// indexMeta 文件的索引状态
+type indexMeta struct {
+	Bucket         string         `json:"bucket"` // bucketData 中保存该文件倒排数据的子 bucket
+	Size           int64          `json:"size"`   // 索引时的文件大小
+	End            int64          `json:"end"`    // 已索引到的偏移，压缩文件为解压后的偏移
+	Count          int            `json:"count"`
+	Levels         map[string]int `json:"levels"`
+	Offsets        []int64        `json:"offsets"`        // 第 i*checkpointInterval 条记录的起始偏移，与分页索引相同
+	Fingerprint    string         `json:"fingerprint"`    // 文件开头 FingerprintLen 字节的 SHA-256
+	FingerprintLen int64          `json:"fingerprintLen"` // 未压缩文件不超过 End
+	Complete       bool           `json:"complete"`       // 压缩文件已全部索引
+}
+
+// Indexer 返回持久索引，首次调用时打开 Config.IndexFile，未配置时返回 nil
+func (lv *LogViewer) Indexer() (*Indexer, error) {
+	if lv.config.IndexFile == "" {
+		return nil, nil
+	}
+	lv.indexerMu.Lock()
+	defer lv.indexerMu.Unlock()
+	if lv.indexer == nil && lv.indexerErr == nil {
+		lv.indexer, lv.indexerErr = openIndexer(lv)
+		if lv.indexerErr != nil {
+			slog.Error("goslogviewer: failed to open index, searching without it", "file", lv.config.IndexFile, "err", lv.indexerErr)
+		}
+	}
+	return lv.indexer, lv.indexerErr
+}
+
+// persistentIndex 返回可用的持久索引，未配置或打开失败时返回 nil
+func (lv *LogViewer) persistentIndex() *Indexer {
+	ix, _ := lv.Indexer()
+	return ix
+}
+
+func openIndexer(lv *LogViewer) (*Indexer, error) {
+	if err := os.MkdirAll(filepath.Dir(lv.config.IndexFile), 0755); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(lv.config.IndexFile, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	ix := &Indexer{lv: lv, db: db, attrs: make(map[string]bool)}
+	for _, key := range lv.config.IndexAttrs {
+		ix.attrs[key] = true
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(bucketMeta)
+		if err != nil {
+			return err
+		}
+		// 格式版本或索引的属性变化时重建
+		attrs, _ := json.Marshal(lv.config.IndexAttrs)
+		if string(meta.Get([]byte("version"))) != indexVersion || !bytes.Equal(meta.Get([]byte("attrs")), attrs) {
+			if err := resetBuckets(tx); err != nil {
+				return err
+			}
+		}
+		if err := meta.Put([]byte("version"), []byte(indexVersion)); err != nil {
+			return err
+		}
+		return meta.Put([]byte("attrs"), attrs)
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return ix, nil
+}
+
+// resetBuckets 删除全部文件的索引
+func resetBuckets(tx *bolt.Tx) error {
+	for _, name := range [][]byte{bucketFiles, bucketData} {
+		if tx.Bucket(name) != nil {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.CreateBucket(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close 关闭索引文件
+func (ix *Indexer) Close() error {
+	return ix.db.Close()
+}
+
+// Update 增量更新全部日志源（内存日志源除外）中文件的索引，ctx 取消时保留已提交的部分
+func (ix *Indexer) Update(ctx context.Context) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.update(ctx)
+}
+
+// Rebuild 删除现有索引后重新索引全部文件
+func (ix *Indexer) Rebuild(ctx context.Context) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if err := ix.db.Update(resetBuckets); err != nil {
+		return err
+	}
+	return ix.update(ctx)
+}
+
+// Run 立即更新一次索引，之后每隔 Config.IndexInterval（默认 1 分钟）更新，直到 ctx 取消
+func (ix *Indexer) Run(ctx context.Context) {
+	interval := ix.lv.config.IndexInterval
+	if interval <= 0 {
+		interval = defaultIndexInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := ix.Update(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("goslogviewer: failed to update index", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (ix *Indexer) update(ctx context.Context) error {
+	seen := map[string]bool{}
+	for _, src := range ix.lv.Sources() {
+		if src.memory != nil {
+			continue
+		}
+		err := src.walkLogFiles(ListOptions{Recursive: true}, func(name string, _ fs.FileInfo) error {
+			path := filepath.Join(src.config.Dir, filepath.FromSlash(name))
+			if seen[path] {
+				return nil
+			}
+			seen[path] = true
+			if err := ix.updateFile(ctx, src, name, path); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				slog.Warn("goslogviewer: failed to index file", "path", path, "err", err)
+			}
+			return nil
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			slog.Warn("goslogviewer: failed to list files for indexing", "source", src.config.Name, "err", err)
+		}
+	}
+	return ix.prune(seen)
+}
+
+// prune 删除已不存在的文件的索引
+func (ix *Indexer) prune(seen map[string]bool) error {
+	return ix.db.Update(func(tx *bolt.Tx) error {
+		files, data := tx.Bucket(bucketFiles), tx.Bucket(bucketData)
+		var stale [][]byte
+		err := files.ForEach(func(k, v []byte) error {
+			if !seen[string(k)] {
+				stale = append(stale, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range stale {
+			if err := deleteFileIndex(files, data, k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// deleteFileIndex 删除文件的索引状态及倒排数据
+func deleteFileIndex(files, data *bolt.Bucket, path []byte) error {
+	var meta indexMeta
+	if err := json.Unmarshal(files.Get(path), &meta); err == nil && data.Bucket([]byte(meta.Bucket)) != nil {
+		if err := data.DeleteBucket([]byte(meta.Bucket)); err != nil {
+			return err
+		}
+	}
+	return files.Delete(path)
+}
+
+// loadMeta 读取文件的索引状态，没有索引时返回 nil
+func loadMeta(tx *bolt.Tx, path string) *indexMeta {
+	v := tx.Bucket(bucketFiles).Get([]byte(path))
+	if v == nil {
+		return nil
+	}
+	var meta indexMeta
+	if err := json.Unmarshal(v, &meta); err != nil {
+		return nil
+	}
+	return &meta
+}
+
+// valid 判断索引是否仍对应该文件：未被截断，且开头的内容没有变化（没有被轮转替换）
+func (m *indexMeta) valid(file *logFile, info fs.FileInfo) bool {
+	if file.compression != "" {
+		if m.Size != info.Size() {
+			return false
+		}
+	} else if info.Size() < m.End {
+		return false
+	}
+	fp, err := fingerprint(file, m.FingerprintLen)
+	return err == nil && fp == m.Fingerprint
+}
+
+// fingerprint 计算文件开头 n 字节的 SHA-256
+func fingerprint(file *logFile, n int64) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(file, 0, n)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// updateFile 增量索引一个文件
+func (ix *Indexer) updateFile(ctx context.Context, src *Source, name, path string) error {
+	file, _, err := src.open(permIndex, name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	var meta *indexMeta
+	ix.db.View(func(tx *bolt.Tx) error {
+		meta = loadMeta(tx, path)
+		return nil
+	})
+	if meta != nil && !meta.valid(file, info) {
+		// 文件被截断或替换，重建索引
+		err := ix.db.Update(func(tx *bolt.Tx) error {
+			return deleteFileIndex(tx.Bucket(bucketFiles), tx.Bucket(bucketData), []byte(path))
+		})
+		if err != nil {
+			return err
+		}
+		meta = nil
+	}
+	if meta != nil && (meta.Complete || (file.compression == "" && meta.End == info.Size())) {
+		return nil
+	}
+	if meta == nil {
+		meta = &indexMeta{Levels: make(map[string]int)}
+	}
+
+	parser := ix.lv.fileParser(name, file)
+	rc, err := file.reader(meta.End)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	var r io.Reader = rc
+	if file.compression == "" {
+		// 只索引到 Stat 时的大小，之后追加的内容留给下次更新
+		r = io.LimitReader(rc, info.Size()-meta.End)
+	}
+
+	batch := &indexBatch{}
+	var flushErr error
+	err = readLines(r, meta.End, func(line []byte, start, end int64) bool {
+		if file.compression == "" && end == info.Size() && !file.complete(end) {
+			return false // 最后一行尚未写完
+		}
+		if entry, ok := parser.Parse(line); ok {
+			if meta.Count%checkpointInterval == 0 {
+				meta.Offsets = append(meta.Offsets, start)
+			}
+			meta.Count++
+			meta.Levels[levelName(entry.Level)]++
+			batch.add(ix.attrs, &entry, start)
+		}
+		meta.End = end
+		if batch.n >= indexBatchSize {
+			if flushErr = ix.flush(path, meta, file, info, batch); flushErr == nil {
+				flushErr = ctx.Err()
+			}
+			batch = &indexBatch{}
+			return flushErr == nil
+		}
+		return true
+	})
+	if err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		return err
+	}
+	if file.compression != "" {
+		meta.Complete = true
+	}
+	return ix.flush(path, meta, file, info, batch)
+}
+
+// flush 在一个写事务中保存一批倒排数据及文件的索引状态
+func (ix *Indexer) flush(path string, meta *indexMeta, file *logFile, info fs.FileInfo, batch *indexBatch) error {
+	meta.Size = info.Size()
+	fpLen := min(int64(fingerprintSize), meta.End)
+	if file.compression != "" {
+		fpLen = min(int64(fingerprintSize), info.Size())
+	}
+	if fpLen != meta.FingerprintLen || meta.Fingerprint == "" {
+		fp, err := fingerprint(file, fpLen)
+		if err != nil {
+			return err
+		}
+		meta.Fingerprint, meta.FingerprintLen = fp, fpLen
+	}
+
+	return ix.db.Update(func(tx *bolt.Tx) error {
+		files, data := tx.Bucket(bucketFiles), tx.Bucket(bucketData)
+		if meta.Bucket == "" {
+			seq, err := data.NextSequence()
+			if err != nil {
+				return err
+			}
+			meta.Bucket = "f" + strconv.FormatUint(seq, 10)
+		}
+		b, err := data.CreateBucketIfNotExists([]byte(meta.Bucket))
+		if err != nil {
+			return err
+		}
+		for name, keys := range map[string][][]byte{
+			string(bucketTime):  batch.time,
+			string(bucketLevel): batch.level,
+			string(bucketAttr):  batch.attr,
+			string(bucketToken): batch.token,
+		} {
+			sub, err := b.CreateBucketIfNotExists([]byte(name))
+			if err != nil {
+				return err
+			}
+			// 按键排序后写入，减少页分裂
+			sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+			for _, k := range keys {
+				if err := sub.Put(k, nil); err != nil {
+					return err
+				}
+			}
+		}
+		v, err := json.Marshal(meta)
+		if err != nil {
+			return err
+		}
+		return files.Put([]byte(path), v)
+	})
+}
+
+// indexBatch 待写入的倒排数据
+type indexBatch struct {
+	n                        int
+	time, level, attr, token [][]byte
+}
+
+// add 添加一条记录的倒排数据
+func (b *indexBatch) add(attrs map[string]bool, entry *LogEntry, offset int64) {
+	b.n++
+	off := binary.BigEndian.AppendUint64(nil, uint64(offset))
+	if t, err := ParseTime(entry.Time); err == nil {
+		b.time = append(b.time, append(timeIndexKey(t), off...))
+	}
+	if level, err := ParseLevel(entry.Level); err == nil {
+		b.level = append(b.level, append(levelIndexKey(level), off...))
+	}
+	for key := range attrs {
+		if v, ok := lookupAttr(entry.Attrs, key); ok {
+			if value, ok := indexValue(v); ok {
+				b.attr = append(b.attr, append(attrPrefix(key, value), off...))
+			}
+		}
+	}
+	for _, token := range messageTokens(entry.Msg) {
+		b.token = append(b.token, append(tokenPrefix(token), off...))
+	}
+}
+
+// indexValue 返回属性值在索引中的文本，与 Filter.Attrs 的比较方式相同，只索引较短的标量值
+func indexValue(v interface{}) (string, bool) {
+	switch v.(type) {
+	case string, json.Number, bool, float64:
+	default:
+		return "", false
+	}
+	s := fmt.Sprint(v)
+	return s, len(s) <= maxIndexValue && !bytes.ContainsRune([]byte(s), 0)
+}
+
+// attrIndexable 判断属性等值条件能否使用属性索引。过长或含 NUL 的值，以及可能等于数组、对象或 null 的文本的值没有写入索引，
+// 这些条件的空结果不代表没有匹配，需要逐条扫描
+func attrIndexable(want string) bool {
+	if _, ok := indexValue(want); !ok {
+		return false
+	}
+	return want != "<nil>" && !strings.HasPrefix(want, "[") && !strings.HasPrefix(want, "map[")
+}
+
+// timeIndexKey 时间的可排序编码
+func timeIndexKey(t time.Time) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(t.UnixNano())^(1<<63))
+}
+
+// levelIndexKey 级别的可排序编码
+func levelIndexKey(level slog.Level) []byte {
+	return binary.BigEndian.AppendUint32(nil, uint32(int32(level))^(1<<31))
+}
+
+func attrPrefix(key, value string) []byte {
+	return []byte(key + "\x00" + value + "\x00")
+}
+
+func tokenPrefix(token string) []byte {
+	return []byte(token + "\x00")
+}
+
+// pageIndex 从持久索引生成分页索引：压缩文件直接使用，未压缩文件作为增量更新的起点，索引与文件不一致时返回 nil
+func (ix *Indexer) pageIndex(path string, file *logFile, info fs.FileInfo) *fileIndex {
+	var meta *indexMeta
+	ix.db.View(func(tx *bolt.Tx) error {
+		meta = loadMeta(tx, path)
+		return nil
+	})
+	if meta == nil || !meta.valid(file, info) {
+		return nil
+	}
+	idx := &fileIndex{size: meta.End, end: meta.End, count: meta.Count, offsets: meta.Offsets, levels: meta.Levels}
+	if file.compression != "" {
+		if !meta.Complete {
+			return nil
+		}
+		idx.size, idx.modTime = info.Size(), info.ModTime()
+		idx.length, idx.total = meta.End, meta.Count
+	}
+	if idx.levels == nil {
+		idx.levels = make(map[string]int)
+	}
+	return idx
+}
+
+// candidates 根据过滤条件中可以使用索引的条件（级别、时间、已索引的属性、分词）返回已索引部分中的候选记录偏移（升序）
+// 及已索引到的偏移，只使用候选最少的一个条件，其余条件由调用方逐条检查。
+// 没有可用条件、条件匹配超过一半的记录或索引与文件不一致时返回 false
+func (ix *Indexer) candidates(path string, file *logFile, filter *Filter) ([]int64, int64, bool) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, false
+	}
+	var (
+		best  []int64
+		found bool
+		end   int64
+	)
+	ix.db.View(func(tx *bolt.Tx) error {
+		meta := loadMeta(tx, path)
+		if meta == nil || !meta.valid(file, info) || (file.compression != "" && !meta.Complete) {
+			return nil
+		}
+		data := tx.Bucket(bucketData).Bucket([]byte(meta.Bucket))
+		if data == nil {
+			return nil
+		}
+		limit := meta.Count/2 + 1
+		try := func(bucket []byte, from, to []byte) {
+			if list, ok := scanPostings(data.Bucket(bucket), from, to, limit); ok {
+				best, found, limit = list, true, len(list)
+			}
+		}
+
+		if filter.MinLevel != nil {
+			try(bucketLevel, levelIndexKey(*filter.MinLevel), nil)
+		}
+		if !filter.From.IsZero() || !filter.To.IsZero() {
+			var from, to []byte
+			if !filter.From.IsZero() {
+				from = timeIndexKey(filter.From)
+			}
+			if !filter.To.IsZero() {
+				to = timeIndexKey(filter.To)
+			}
+			try(bucketTime, from, to)
+		}
+		for key, want := range filter.Attrs {
+			if ix.attrs[key] && attrIndexable(want) {
+				prefix := attrPrefix(key, want)
+				try(bucketAttr, prefix, prefixEnd(prefix))
+			}
+		}
+		for _, term := range filter.Terms {
+			for _, token := range messageTokens(term) {
+				prefix := tokenPrefix(token)
+				try(bucketToken, prefix, prefixEnd(prefix))
+			}
+		}
+		end = meta.End
+		return nil
+	})
+	if !found {
+		return nil, 0, false
+	}
+	sort.Slice(best, func(i, j int) bool { return best[i] < best[j] })
+	return best, end, true
+}
+
+// scanPostings 读取 [from, to) 范围内的键末尾的偏移，from 为 nil 时从头开始，to 为 nil 时到末尾，超过 limit 条时返回 false
+func scanPostings(b *bolt.Bucket, from, to []byte, limit int) ([]int64, bool) {
+	if b == nil {
+		return nil, true
+	}
+	c := b.Cursor()
+	k, _ := c.First()
+	if from != nil {
+		k, _ = c.Seek(from)
+	}
+	var offsets []int64
+	for ; k != nil && (to == nil || bytes.Compare(k, to) < 0); k, _ = c.Next() {
+		if len(offsets) >= limit {
+			return nil, false
+		}
+		offsets = append(offsets, int64(binary.BigEndian.Uint64(k[len(k)-8:])))
+	}
+	return offsets, true
+}
+
+// prefixEnd 返回大于全部以 prefix 开头的键的最小键
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte(nil), prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// scanFiltered 从 from 偏移处开始读取满足过滤条件的记录，回调返回 false 或 ctx 取消时停止。
+// 有持久索引时已索引部分只读取候选行，之后追加的内容顺序读取
+func (s *Source) scanFiltered(ctx context.Context, file *logFile, path string, parser LineParser, filter *Filter, from int64, fn func(entry *LogEntry, start, end int64) bool) error {
+	lines := 0
+	emit := func(line []byte, start, end int64) bool {
+		if lines++; lines%256 == 0 && ctx.Err() != nil {
+			return false
+		}
+		entry, ok := parser.Parse(line)
+		if !ok || !filter.Match(&entry) {
+			return true
+		}
+		return fn(&entry, start, end)
+	}
+
+	if ix := s.lv.persistentIndex(); ix != nil && !file.memory && !filter.Empty() {
+		if offsets, end, ok := ix.candidates(path, file, filter); ok && end > from {
+			offsets = offsets[sort.Search(len(offsets), func(i int) bool { return offsets[i] >= from }):]
+			stopped, err := readCandidates(file, offsets, emit)
+			if err != nil || stopped {
+				return err
+			}
+			from = end
+		}
+	}
+
+	r, err := file.reader(from)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return readLines(r, from, emit)
+}
+
+// readCandidates 按升序偏移读取各行，回调返回 false 时停止并返回 true。压缩文件需要从头解压，只跳过解析
+func readCandidates(file *logFile, offsets []int64, fn func(line []byte, start, end int64) bool) (bool, error) {
+	if len(offsets) == 0 {
+		return false, nil
+	}
+	stopped := false
+	if file.compression != "" {
+		r, err := file.reader(0)
+		if err != nil {
+			return false, err
+		}
+		defer r.Close()
+		i := 0
+		err = readLines(r, 0, func(line []byte, start, end int64) bool {
+			for i < len(offsets) && offsets[i] < start {
+				i++
+			}
+			if i < len(offsets) && offsets[i] == start {
+				i++
+				if !fn(line, start, end) {
+					stopped = true
+					return false
+				}
+			}
+			return i < len(offsets)
+		})
+		return stopped, err
+	}
+
+	const bufSize = 4096
+	br := bufio.NewReaderSize(file, bufSize)
+	pos := int64(-1)
+	for _, off := range offsets {
+		if pos < 0 || off < pos || off-pos > bufSize {
+			if _, err := file.Seek(off, io.SeekStart); err != nil {
+				return false, err
+			}
+			br.Reset(file)
+		} else if _, err := br.Discard(int(off - pos)); err != nil {
+			return false, err
+		}
+		line, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return false, err
+		}
+		if len(line) == 0 {
+			break
+		}
+		pos = off + int64(len(line))
+		if !fn(bytes.TrimRight(line, "\r\n"), off, pos) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,316 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/18 06:48:21
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/18 06:48:21
+ * Description: 持久索引测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// indexLine 第 i 条测试日志，每 10 条一条 ERROR，request_id 在 r0~r2 之间循环
+func indexLine(i int) string {
+	level := "INFO"
+	if i%10 == 0 {
+		level = "ERROR"
+	}
+	return fmt.Sprintf(`{"time":"2026-10-01T%02d:%02d:00Z","level":"%s","msg":"order %d 已处理 step-%d","request_id":"r%d"}`+"\n",
+		10+i/60, i%60, level, i, i%7, i%3)
+}
+
+// writeIndexLines 追加第 from 到 to-1 条测试日志
+func writeIndexLines(t *testing.T, path string, from, to int) {
+	t.Helper()
+	var b strings.Builder
+	for i := from; i < to; i++ {
+		b.WriteString(indexLine(i))
+	}
+	appendLine(t, path, b.String())
+}
+
+// newIndexedViewer 返回配置了持久索引的 LogViewer
+func newIndexedViewer(t *testing.T, logDir, indexFile string) (*LogViewer, *Indexer) {
+	t.Helper()
+	lv := New(&Config{LogDir: logDir, IndexFile: indexFile, IndexAttrs: []string{"request_id"}})
+	t.Cleanup(func() { lv.Close() })
+	ix, err := lv.Indexer()
+	if err != nil || ix == nil {
+		t.Fatalf("Failed to open index: %v", err)
+	}
+	return lv, ix
+}
+
+// indexState 返回文件的索引状态
+func indexState(t *testing.T, ix *Indexer, path string) *indexMeta {
+	t.Helper()
+	var meta *indexMeta
+	ix.db.View(func(tx *bolt.Tx) error {
+		meta = loadMeta(tx, path)
+		return nil
+	})
+	return meta
+}
+
+// searchMsgs 返回搜索结果的消息
+func searchMsgs(t *testing.T, s *Source, filter *Filter) []string {
+	t.Helper()
+	var got []string
+	_, err := s.Search(context.Background(), SearchOptions{Filter: filter, Limit: maxSearchLimit, Recursive: true}, func(r SearchResult) error {
+		got = append(got, r.Entry.Msg)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	return got
+}
+
+// checkSameResults 比较使用索引与不使用索引的搜索结果
+func checkSameResults(t *testing.T, indexed *LogViewer, logDir string, filters ...*Filter) {
+	t.Helper()
+	plain := New(&Config{LogDir: logDir})
+	for _, filter := range filters {
+		want := searchMsgs(t, plain.defaultSource(), filter)
+		got := searchMsgs(t, indexed.defaultSource(), filter)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%+v: expected %d results %v, got %d %v", filter, len(want), want, len(got), got)
+		}
+	}
+}
+
+func testFilters() []*Filter {
+	level, _ := ParseLevel("ERROR")
+	q, _ := ParseQuery(`msg~"order 1"`)
+	return []*Filter{
+		{MinLevel: &level},
+		{Attrs: map[string]string{"request_id": "r1"}, Terms: []string{"step-3"}},
+		{Terms: []string{"ORDER", "42"}},
+		{Terms: []string{"处理"}, Query: q},
+		{From: mustTime("2026-10-01T10:30:00Z"), To: mustTime("2026-10-01T10:33:00Z")},
+	}
+}
+
+func mustTime(s string) time.Time {
+	t, _ := ParseTime(s)
+	return t
+}
+
+func TestIndexer_Update(t *testing.T) {
+	logDir := t.TempDir()
+	path := filepath.Join(logDir, "app.log")
+	writeIndexLines(t, path, 0, 200)
+	appendLine(t, path, `{"time":"2026-10-01T13:00:00Z","level":"ERROR","msg":"order partial`) // 未写完的行
+
+	lv, ix := newIndexedViewer(t, logDir, filepath.Join(t.TempDir(), "index", "index.db"))
+	if err := ix.Update(context.Background()); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	meta := indexState(t, ix, path)
+	if meta == nil || meta.Count != 200 || meta.Levels["ERROR"] != 20 {
+		t.Fatalf("Unexpected index state: %+v", meta)
+	}
+	file, _, _ := lv.defaultSource().open(PermView, "app.log")
+	offsets, end, ok := ix.candidates(path, file, &Filter{Terms: []string{"order", "42"}})
+	file.Close()
+	if !ok || len(offsets) != 1 || end != meta.End {
+		t.Errorf("Expected a single candidate from the token index, got %v %d %v", offsets, end, ok)
+	}
+	checkSameResults(t, lv, logDir, testFilters()...)
+
+	// 追加：补全未写完的行后继续索引，索引之前的新内容也能搜索到
+	appendLine(t, path, " 42\"}\n")
+	writeIndexLines(t, path, 200, 260)
+	checkSameResults(t, lv, logDir, testFilters()...)
+	if err := ix.Update(context.Background()); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if meta2 := indexState(t, ix, path); meta2.Count != 261 || meta2.Bucket != meta.Bucket {
+		t.Errorf("Expected incremental update, got %+v", meta2)
+	}
+	checkSameResults(t, lv, logDir, testFilters()...)
+
+	// 截断：更新前不使用失效的索引，更新后重建
+	os.WriteFile(path, nil, 0644)
+	writeIndexLines(t, path, 0, 30)
+	checkSameResults(t, lv, logDir, testFilters()...)
+	if err := ix.Update(context.Background()); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if meta3 := indexState(t, ix, path); meta3.Count != 30 || meta3.Bucket == meta.Bucket {
+		t.Errorf("Expected index to be rebuilt after truncation, got %+v", meta3)
+	}
+
+	// 轮转：文件被替换为开头不同、长度不小于原文件的新文件
+	os.Rename(path, path+".1")
+	writeIndexLines(t, path, 100, 140)
+	checkSameResults(t, lv, logDir, testFilters()...)
+	if err := ix.Update(context.Background()); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if rotated := indexState(t, ix, path+".1"); rotated == nil || rotated.Count != 30 {
+		t.Errorf("Expected rotated file to be indexed, got %+v", rotated)
+	}
+	if meta4 := indexState(t, ix, path); meta4.Count != 40 {
+		t.Errorf("Expected new file to be indexed, got %+v", meta4)
+	}
+	checkSameResults(t, lv, logDir, testFilters()...)
+
+	// 删除的文件不再保留索引
+	os.Remove(path + ".1")
+	if err := ix.Update(context.Background()); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if indexState(t, ix, path+".1") != nil {
+		t.Error("Expected index of removed file to be pruned")
+	}
+}
+
+func TestIndexer_Rebuild(t *testing.T) {
+	logDir := t.TempDir()
+	writeIndexLines(t, filepath.Join(logDir, "app.log"), 0, 120)
+	writeCompressed(t, filepath.Join(logDir, "old.log.gz"), 50)
+	indexFile := filepath.Join(t.TempDir(), "index.db")
+
+	lv, ix := newIndexedViewer(t, logDir, indexFile)
+	if err := ix.Update(context.Background()); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	before := indexState(t, ix, filepath.Join(logDir, "app.log"))
+	gz := indexState(t, ix, filepath.Join(logDir, "old.log.gz"))
+	if gz == nil || !gz.Complete || gz.Count != 50 {
+		t.Fatalf("Expected compressed file to be fully indexed, got %+v", gz)
+	}
+
+	if err := ix.Rebuild(context.Background()); err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+	after := indexState(t, ix, filepath.Join(logDir, "app.log"))
+	if after.Count != before.Count || after.End != before.End || after.Fingerprint != before.Fingerprint {
+		t.Errorf("Expected rebuilt index to match, got %+v and %+v", before, after)
+	}
+	checkSameResults(t, lv, logDir, testFilters()...)
+
+	// 索引的属性变化时打开索引会清空原有数据
+	lv.Close()
+	lv2 := New(&Config{LogDir: logDir, IndexFile: indexFile, IndexAttrs: []string{"user_id"}})
+	defer lv2.Close()
+	ix2, err := lv2.Indexer()
+	if err != nil {
+		t.Fatalf("Failed to reopen index: %v", err)
+	}
+	if indexState(t, ix2, filepath.Join(logDir, "app.log")) != nil {
+		t.Error("Expected index to be reset after IndexAttrs changed")
+	}
+}
+
+func TestIndexer_UnindexedAttrValues(t *testing.T) {
+	logDir := t.TempDir()
+	path := filepath.Join(logDir, "app.log")
+	long := strings.Repeat("x", 200)
+	writeIndexLines(t, path, 0, 20)
+	appendLine(t, path, `{"time":"2026-10-01T11:00:00Z","level":"INFO","msg":"long","request_id":"`+long+`"}`+"\n")
+	appendLine(t, path, `{"time":"2026-10-01T11:01:00Z","level":"INFO","msg":"nul","request_id":"a\u0000b"}`+"\n")
+	appendLine(t, path, `{"time":"2026-10-01T11:02:00Z","level":"INFO","msg":"list","request_id":["r1"]}`+"\n")
+
+	lv, ix := newIndexedViewer(t, logDir, filepath.Join(t.TempDir(), "index.db"))
+	if err := ix.Update(context.Background()); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	// 未写入属性索引的值不能使用索引的空结果，需要回退到逐条扫描
+	for _, want := range []string{long, "a\x00b", "[r1]"} {
+		filter := &Filter{Attrs: map[string]string{"request_id": want}}
+		if got := searchMsgs(t, lv.defaultSource(), filter); len(got) != 1 {
+			t.Errorf("%.20q: expected 1 result, got %v", want, got)
+		}
+		page, err := lv.defaultSource().GetLogPage("app.log", PageQuery{Filter: filter})
+		if err != nil || page.Total != 1 {
+			t.Errorf("%.20q: expected total 1, got %+v %v", want, page, err)
+		}
+	}
+	checkSameResults(t, lv, logDir, testFilters()...)
+}
+
+func TestIndexer_Pagination(t *testing.T) {
+	logDir := t.TempDir()
+	path := filepath.Join(logDir, "app.log")
+	writeIndexLines(t, path, 0, 2500)
+	writeCompressed(t, filepath.Join(logDir, "old.log.gz"), 30)
+	indexFile := filepath.Join(t.TempDir(), "index.db")
+
+	lv, ix := newIndexedViewer(t, logDir, indexFile)
+	if err := ix.Update(context.Background()); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	lv.Close()
+
+	// 重启后分页索引从持久索引继续
+	lv, _ = newIndexedViewer(t, logDir, indexFile)
+	writeIndexLines(t, path, 2500, 2510)
+	s := lv.defaultSource()
+	page, err := s.GetLogPage("app.log", PageQuery{Page: 251, PageSize: 10})
+	if err != nil || page.Total != 2510 || len(page.Entries) != 10 || page.Entries[0].Msg != "order 2500 已处理 step-1" {
+		t.Fatalf("Unexpected page: %+v %v", page, err)
+	}
+	if idx := lv.indexes.get(path); len(idx.offsets) != 3 || idx.count != 2510 {
+		t.Errorf("Unexpected page index: %+v", idx)
+	}
+	if page, err := s.GetLogPage("old.log.gz", PageQuery{Page: 3, PageSize: 10}); err != nil || page.Total != 30 || len(page.Entries) != 10 {
+		t.Errorf("Unexpected compressed page: %+v %v", page, err)
+	}
+
+	// 带过滤条件的分页使用候选记录，结果与不使用索引时相同
+	plain := New(&Config{LogDir: logDir})
+	values := url.Values{"terms": {"step-3"}, "attr.request_id": {"r2"}}
+	filter, _ := ParseFilter(values)
+	for _, q := range []PageQuery{{Page: 2, PageSize: 20, Filter: filter}, {Page: 1, PageSize: 50, Filter: filter}} {
+		want, _ := plain.defaultSource().GetLogPage("app.log", q)
+		got, err := s.GetLogPage("app.log", q)
+		if err != nil || got.Total != want.Total || !reflect.DeepEqual(got.Entries, want.Entries) || got.NextCursor != want.NextCursor {
+			t.Errorf("Expected %+v, got %+v %v", want, got, err)
+		}
+		if want.NextCursor != "" {
+			q.Cursor, q.Page = want.NextCursor, 0
+			want, _ = plain.defaultSource().GetLogPage("app.log", q)
+			got, _ = s.GetLogPage("app.log", q)
+			if !reflect.DeepEqual(got.Entries, want.Entries) {
+				t.Errorf("Cursor page differs: %+v %+v", want, got)
+			}
+		}
+	}
+}
+
+func TestFilter_Terms(t *testing.T) {
+	if got := messageTokens("Order #42 已处理, step-3 ORDER"); !reflect.DeepEqual(got, []string{"order", "42", "已", "处", "理", "step", "3"}) {
+		t.Errorf("Unexpected tokens: %v", got)
+	}
+	filter, err := ParseFilter(url.Values{"terms": {"order  处理"}})
+	if err != nil || !reflect.DeepEqual(filter.Terms, []string{"order", "处理"}) {
+		t.Fatalf("Unexpected filter: %+v %v", filter, err)
+	}
+	for msg, want := range map[string]bool{
+		"order 1 已处理": true,
+		"orders 已处理":  false,
+		"ORDER 处理中":   true,
+		"order 处置":    false,
+		"reorder 已处理": false,
+	} {
+		if got := filter.Match(&LogEntry{Msg: msg}); got != want {
+			t.Errorf("%q: expected %v, got %v", msg, want, got)
+		}
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -101,7 +102,7 @@ func TestIntegration(t *testing.T) {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
 
-	if len(contentResponse.Data) != 1 || contentResponse.Data[0] != testEntry {
+	if len(contentResponse.Data) != 1 || !reflect.DeepEqual(contentResponse.Data[0], testEntry) {
 		t.Errorf("Entry mismatch: expected %v, got %v", testEntry, contentResponse.Data[0])
 	}
 
@@ -0,0 +1,136 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/18 11:20:36
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/18 11:20:36
+ * Description: 各框架适配器共用的测试，适配器只需提供启动服务的方法
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package adaptertest
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zjguoxin/goslogviewer"
+)
+
+// Serve 用适配器注册 lv 的路由并启动服务，返回服务地址，服务在测试结束时关闭
+type Serve func(t *testing.T, lv *goslogviewer.LogViewer) string
+
+// Routes 检查页面、静态资源及接口的路由和响应类型
+func Routes(t *testing.T, serve Serve) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "app.log"), []byte(`{"level":"INFO","msg":"hello"}`+"\n"), 0644)
+	url := serve(t, goslogviewer.New(&goslogviewer.Config{LogDir: tempDir}))
+
+	tests := []struct {
+		path        string
+		status      int
+		want        string
+		contentType string
+	}{
+		{"/log", http.StatusOK, `const base = "/log"`, "text/html"},
+		{"/log/static/dashboard.css", http.StatusOK, "", "text/css"},
+		{"/log/getLogFilesList", http.StatusOK, `"app.log"`, "application/json"},
+		{"/log/getFileContent?name=app.log", http.StatusOK, `"hello"`, "application/json"},
+		{"/log/getFileContent?name=../app.log", http.StatusBadRequest, "", ""},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(url + tt.path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", tt.path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.status || !strings.Contains(string(body), tt.want) {
+			t.Errorf("GET %s: got %d %q", tt.path, resp.StatusCode, body)
+		}
+		if !strings.HasPrefix(resp.Header.Get("Content-Type"), tt.contentType) {
+			t.Errorf("GET %s: unexpected content type %s", tt.path, resp.Header.Get("Content-Type"))
+		}
+	}
+}
+
+// IPRestriction 检查启用IP限制后拒绝白名单之外的地址
+func IPRestriction(t *testing.T, serve Serve) {
+	url := serve(t, goslogviewer.New(&goslogviewer.Config{
+		LogDir:              t.TempDir(),
+		EnableIPRestriction: true,
+		AllowedIPs:          []string{"10.1.1.1"},
+	}))
+
+	resp, err := http.Get(url + "/log")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status Forbidden, got %d", resp.StatusCode)
+	}
+}
+
+// Tail 检查 SSE 能在追加日志后立即推送，HEAD 只返回响应头
+func Tail(t *testing.T, serve Serve) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "app.log")
+	os.WriteFile(path, nil, 0644)
+	url := serve(t, goslogviewer.New(&goslogviewer.Config{LogDir: tempDir, TailInterval: 20 * time.Millisecond}))
+	client := &http.Client{Transport: &http.Transport{MaxConnsPerHost: 1}}
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Head(url + "/log/tail?name=app.log")
+	if err != nil {
+		t.Fatalf("HEAD failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("HEAD: unexpected response %d %v", resp.StatusCode, resp.Header)
+	}
+
+	resp, err = client.Get(url + "/log/tail?name=app.log")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Unexpected content type: %s", resp.Header.Get("Content-Type"))
+	}
+
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"level":"INFO","msg":"followed"}` + "\n")
+	f.Close()
+
+	if line := readEvent(t, resp.Body); !strings.Contains(line, "followed") {
+		t.Errorf("Unexpected event: %s", line)
+	}
+}
+
+// readEvent 读取第一条 data 行
+func readEvent(t *testing.T, r io.Reader) string {
+	t.Helper()
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			if strings.HasPrefix(scanner.Text(), "data: ") {
+				lines <- scanner.Text()
+				return
+			}
+		}
+		close(lines)
+	}()
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+		return ""
+	}
+}
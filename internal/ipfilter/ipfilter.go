@@ -0,0 +1,160 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 18:42:10
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 18:42:10
+ * Description: IP白名单校验，供 net/http 处理器和各框架中间件共用
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package ipfilter
+
+import (
+	"net"
+	"strings"
+	"sync"
+)
+
+var (
+	ipNetCache = make(map[string]*net.IPNet)
+	cacheMutex sync.RWMutex
+)
+
+// Filter IP白名单
+type Filter struct {
+	allowedIPs      []string
+	trustedProxies  []string
+	compiledAllowed []*net.IPNet
+	compiledProxies []*net.IPNet
+}
+
+// New 创建IP白名单，预编译允许的IP段及可信代理
+func New(allowedIPs, trustedProxies []string) *Filter {
+	return &Filter{
+		allowedIPs:      allowedIPs,
+		trustedProxies:  trustedProxies,
+		compiledAllowed: compileCIDRs(allowedIPs),
+		compiledProxies: compileCIDRs(trustedProxies),
+	}
+}
+
+// ClientIP 获取客户端真实IP，remoteIP 为直连地址（可带端口），
+// forwardedFor 为 X-Forwarded-For 头部，从右往左跳过可信代理
+func (f *Filter) ClientIP(remoteIP, forwardedFor string) string {
+	ipStr := remoteIP
+
+	// 1. 去除直连地址的端口号
+	if host, _, err := net.SplitHostPort(ipStr); err == nil {
+		ipStr = host
+	}
+
+	// 2. 处理X-Forwarded-For头部
+	if forwardedFor != "" {
+		ips := strings.Split(forwardedFor, ",")
+		for i := len(ips) - 1; i >= 0; i-- {
+			candidate := strings.TrimSpace(ips[i])
+			if !isIPInCIDRs(candidate, f.compiledProxies) {
+				ipStr = candidate
+				break
+			}
+		}
+	}
+
+	// 3. 去除端口号
+	if host, _, err := net.SplitHostPort(ipStr); err == nil {
+		ipStr = host
+	}
+
+	// 4. 解析出客户端地址后再将IPv6本地地址视为127.0.0.1，可信代理位于::1时不能跳过X-Forwarded-For
+	if ip := net.ParseIP(ipStr); ip != nil && ip.Equal(net.IPv6loopback) {
+		return "127.0.0.1"
+	}
+
+	return ipStr
+}
+
+// Allowed 检查IP是否被允许
+func (f *Filter) Allowed(ipStr string) bool {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return false
+	}
+
+	// 1. 检查精确匹配
+	for _, allowed := range f.allowedIPs {
+		if allowed == ipStr || allowed == "*" {
+			return true
+		}
+	}
+
+	// 2. 检查CIDR范围
+	for _, ipNet := range f.compiledAllowed {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TrustedProxy 检查直连地址（可带端口）是否为可信代理
+func (f *Filter) TrustedProxy(remoteIP string) bool {
+	if host, _, err := net.SplitHostPort(remoteIP); err == nil {
+		remoteIP = host
+	}
+	for _, proxy := range f.trustedProxies {
+		if proxy == remoteIP {
+			return true
+		}
+	}
+	return isIPInCIDRs(remoteIP, f.compiledProxies)
+}
+
+// 编译CIDR列表为IPNet对象
+func compileCIDRs(cidrs []string) []*net.IPNet {
+	var result []*net.IPNet
+	for _, cidr := range cidrs {
+		if strings.Contains(cidr, "/") {
+			if ipNet := parseCIDR(cidr); ipNet != nil {
+				result = append(result, ipNet)
+			}
+		}
+	}
+	return result
+}
+
+// 检查IP是否在CIDR列表中
+func isIPInCIDRs(ipStr string, ipNets []*net.IPNet) bool {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return false
+	}
+
+	for _, ipNet := range ipNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// 解析CIDR并缓存
+func parseCIDR(cidr string) *net.IPNet {
+	cacheMutex.RLock()
+	ipNet, exists := ipNetCache[cidr]
+	cacheMutex.RUnlock()
+
+	if exists {
+		return ipNet
+	}
+
+	_, ipNetTmp, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil
+	}
+
+	cacheMutex.Lock()
+	ipNetCache[cidr] = ipNetTmp
+	cacheMutex.Unlock()
+
+	return ipNetTmp
+}
@@ -14,6 +14,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -101,7 +102,7 @@ func TestGetContentHandler(t *testing.T) {
 		t.Fatalf("Expected 1 entry, got %d", len(response.Data))
 	}
 
-	if response.Data[0] != testEntry {
+	if !reflect.DeepEqual(response.Data[0], testEntry) {
 		t.Errorf("Entry mismatch: expected %v, got %v", testEntry, response.Data[0])
 	}
 }
@@ -219,7 +220,8 @@ func TestGetLogContent_EmptyFile(t *testing.T) {
 
 func TestGetLogContent_InvalidJSON(t *testing.T) {
 	tempDir := t.TempDir()
-	config := &Config{LogDir: tempDir}
+	// 指定 JSON 格式，自动识别会将其作为纯文本读取
+	config := &Config{LogDir: tempDir, Format: FormatJSON}
 	lv := New(config)
 
 	// 创建包含无效JSON的文件
@@ -242,6 +244,45 @@ func TestGetLogContent_InvalidJSON(t *testing.T) {
 	}
 }
 
+func TestGetLogContent_Attrs(t *testing.T) {
+	tempDir := t.TempDir()
+	config := &Config{LogDir: tempDir}
+	lv := New(config)
+
+	// 创建包含属性和分组的日志文件
+	testFile := "attrs.log"
+	line := `{"time":"2023-01-01T00:00:00Z","level":"ERROR","msg":"request failed","request_id":"abc","user_id":9007199254740993,"req":{"method":"GET","path":"/api"}}`
+	if err := os.WriteFile(filepath.Join(tempDir, testFile), []byte(line+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	entries, err := lv.GetLogContent(testFile)
+	if err != nil {
+		t.Fatalf("GetLogContent failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+
+	entry := entries[0]
+	if entry.Level != "ERROR" || entry.Msg != "request failed" {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	if entry.Attrs["request_id"] != "abc" {
+		t.Errorf("Expected request_id attr, got %v", entry.Attrs["request_id"])
+	}
+	if id, ok := entry.Attrs["user_id"].(json.Number); !ok || id.String() != "9007199254740993" {
+		t.Errorf("Expected user_id to keep precision, got %v", entry.Attrs["user_id"])
+	}
+	group, ok := entry.Attrs["req"].(map[string]interface{})
+	if !ok || group["path"] != "/api" {
+		t.Errorf("Expected nested group, got %v", entry.Attrs["req"])
+	}
+	if _, ok := entry.Attrs["msg"]; ok {
+		t.Error("Built-in keys should not be duplicated in attrs")
+	}
+}
+
 func TestNewWithDefaultConfig(t *testing.T) {
 	lv := New(nil)
 	if lv.config == nil {
@@ -0,0 +1,341 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/18 02:48:12
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/18 02:48:12
+ * Description: 内存环形缓冲区及 slog.Handler，在没有可写磁盘的环境中查看最近的日志
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// MemorySourceName 内存缓冲区日志源的默认名称，见 Config.MemorySourceName
+	MemorySourceName = "memory"
+	// MemoryFileName 内存缓冲区在文件列表中的文件名
+	MemoryFileName = "memory.log"
+)
+
+// defaultRingRecords 未指定上限时保留的记录条数
+const defaultRingRecords = 10000
+
+// RingBufferOptions 缓冲区上限，两者都指定时先达到的生效，都为 0 时保留最近 10000 条
+type RingBufferOptions struct {
+	MaxRecords int   // 最多保留的记录条数
+	MaxBytes   int64 // 最多保留的字节数，单条超过该大小的记录不保存
+}
+
+// RingBuffer 保存最近日志记录的环形缓冲区，每条记录为一行 JSON，超出上限时丢弃最早的记录
+type RingBuffer struct {
+	mu      sync.Mutex
+	opts    RingBufferOptions
+	records [][]byte // 环形数组，head 为最早的记录
+	head    int
+	n       int
+	size    int64         // 当前保存的字节数
+	seq     uint64        // 已写入的记录总数，即下一条记录的序号
+	resets  uint64        // 清空次数
+	modTime time.Time     // 最后写入或清空的时间
+	changed chan struct{} // 写入或清空时关闭并替换，用于唤醒跟踪
+	dropped uint64        // 超过 MaxBytes 而未保存的记录数
+}
+
+// NewRingBuffer 创建环形缓冲区
+func NewRingBuffer(opts RingBufferOptions) *RingBuffer {
+	if opts.MaxRecords <= 0 && opts.MaxBytes <= 0 {
+		opts.MaxRecords = defaultRingRecords
+	}
+	return &RingBuffer{opts: opts, modTime: time.Now(), changed: make(chan struct{})}
+}
+
+// Write 追加一条记录，p 应为以换行符结尾的一行，缓冲区会保存 p 的副本
+func (b *RingBuffer) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	record := append([]byte(nil), p...)
+	if record[len(record)-1] != '\n' {
+		record = append(record, '\n')
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.opts.MaxBytes > 0 && int64(len(record)) > b.opts.MaxBytes {
+		b.dropped++
+		return len(p), nil
+	}
+	for b.n > 0 && ((b.opts.MaxRecords > 0 && b.n >= b.opts.MaxRecords) ||
+		(b.opts.MaxBytes > 0 && b.size+int64(len(record)) > b.opts.MaxBytes)) {
+		b.evict()
+	}
+	if b.n == len(b.records) {
+		b.grow()
+	}
+	b.records[(b.head+b.n)%len(b.records)] = record
+	b.n++
+	b.size += int64(len(record))
+	b.seq++
+	b.touch()
+	return len(p), nil
+}
+
+// evict 丢弃最早的记录
+func (b *RingBuffer) evict() {
+	b.size -= int64(len(b.records[b.head]))
+	b.records[b.head] = nil
+	b.head = (b.head + 1) % len(b.records)
+	b.n--
+}
+
+// grow 扩大环形数组，按 MaxRecords 限制容量
+func (b *RingBuffer) grow() {
+	capacity := 2 * len(b.records)
+	if capacity < 64 {
+		capacity = 64
+	}
+	if b.opts.MaxRecords > 0 && capacity > b.opts.MaxRecords {
+		capacity = b.opts.MaxRecords
+	}
+	records := make([][]byte, capacity)
+	for i := 0; i < b.n; i++ {
+		records[i] = b.records[(b.head+i)%len(b.records)]
+	}
+	b.records, b.head = records, 0
+}
+
+// touch 更新修改时间并唤醒等待的跟踪，修改时间保证递增，用于判断分页索引是否失效
+func (b *RingBuffer) touch() {
+	now := time.Now()
+	if !now.After(b.modTime) {
+		now = b.modTime.Add(time.Nanosecond)
+	}
+	b.modTime = now
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// Reset 清空缓冲区
+func (b *RingBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.n > 0 {
+		b.evict()
+	}
+	b.head = 0
+	b.resets++
+	b.touch()
+}
+
+// Len 返回当前保存的记录条数
+func (b *RingBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.n
+}
+
+// Size 返回当前保存的字节数
+func (b *RingBuffer) Size() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.size
+}
+
+// Dropped 返回因超过 MaxBytes 而未保存的记录数
+func (b *RingBuffer) Dropped() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dropped
+}
+
+// snapshot 返回当前全部记录拼接后的内容及其文件信息
+func (b *RingBuffer) snapshot() *memoryFile {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data := make([]byte, 0, b.size)
+	for i := 0; i < b.n; i++ {
+		data = append(data, b.records[(b.head+i)%len(b.records)]...)
+	}
+	return &memoryFile{Reader: bytes.NewReader(data), info: b.info()}
+}
+
+// info 返回缓冲区作为文件的信息，调用方需持有锁
+func (b *RingBuffer) info() memoryInfo {
+	return memoryInfo{size: b.size, modTime: b.modTime}
+}
+
+// stat 返回缓冲区作为文件的信息
+func (b *RingBuffer) stat() memoryInfo {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.info()
+}
+
+// since 返回序号不小于 seq 的记录、下一条记录的序号、清空次数及变化通知。
+// 早于 seq 的记录已被丢弃时从最早的记录开始
+func (b *RingBuffer) since(seq uint64) (records [][]byte, next, resets uint64, changed <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	first := b.seq - uint64(b.n)
+	if seq < first {
+		seq = first
+	}
+	for s := seq; s < b.seq; s++ {
+		records = append(records, b.records[(b.head+int(s-first))%len(b.records)])
+	}
+	return records, b.seq, b.resets, b.changed
+}
+
+// memoryFile 缓冲区的只读快照，实现 logFile 需要的文件接口
+type memoryFile struct {
+	*bytes.Reader
+	info memoryInfo
+}
+
+func (f *memoryFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memoryFile) Close() error               { return nil }
+
+// memoryInfo 缓冲区快照的文件信息
+type memoryInfo struct {
+	size    int64
+	modTime time.Time
+}
+
+func (i memoryInfo) Name() string       { return MemoryFileName }
+func (i memoryInfo) Size() int64        { return i.size }
+func (i memoryInfo) Mode() fs.FileMode  { return 0444 }
+func (i memoryInfo) ModTime() time.Time { return i.modTime }
+func (i memoryInfo) IsDir() bool        { return false }
+func (i memoryInfo) Sys() any           { return nil }
+
+// RingHandler 将日志记录交给下一个 Handler 处理，同时以 JSON 格式写入 RingBuffer
+type RingHandler struct {
+	next slog.Handler
+	json slog.Handler
+}
+
+// NewRingHandler 创建 RingHandler，next 为 nil 时只写入缓冲区。
+// opts 用于写入缓冲区的 JSON 编码（级别默认 Info），不影响 next
+func NewRingHandler(next slog.Handler, buf *RingBuffer, opts *slog.HandlerOptions) *RingHandler {
+	return &RingHandler{next: next, json: slog.NewJSONHandler(buf, opts)}
+}
+
+// Enabled 下一个 Handler 或缓冲区接受该级别时返回 true
+func (h *RingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.json.Enabled(ctx, level) || (h.next != nil && h.next.Enabled(ctx, level))
+}
+
+// Handle 分别交给缓冲区及下一个 Handler 处理，返回两者的错误
+func (h *RingHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	if h.json.Enabled(ctx, r.Level) {
+		errs = append(errs, h.json.Handle(ctx, r))
+	}
+	if h.next != nil && h.next.Enabled(ctx, r.Level) {
+		errs = append(errs, h.next.Handle(ctx, r.Clone()))
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs 返回附加了属性的 RingHandler
+func (h *RingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := &RingHandler{json: h.json.WithAttrs(attrs)}
+	if h.next != nil {
+		clone.next = h.next.WithAttrs(attrs)
+	}
+	return clone
+}
+
+// WithGroup 返回开启了分组的 RingHandler
+func (h *RingHandler) WithGroup(name string) slog.Handler {
+	clone := &RingHandler{json: h.json.WithGroup(name)}
+	if h.next != nil {
+		clone.next = h.next.WithGroup(name)
+	}
+	return clone
+}
+
+// memorySource 返回内存缓冲区日志源
+func (lv *LogViewer) memorySource() *Source {
+	return &Source{lv: lv, memory: lv.config.Memory, config: SourceConfig{
+		Name:         lv.memorySourceName(),
+		EnableDelete: lv.config.EnableDelete,
+		EnableClear:  lv.config.EnableClear,
+		EnableExport: lv.config.EnableExport,
+	}}
+}
+
+// memorySourceName 返回内存日志源的名称
+func (lv *LogViewer) memorySourceName() string {
+	if lv.config.MemorySourceName != "" {
+		return lv.config.MemorySourceName
+	}
+	return MemorySourceName
+}
+
+// checkMemorySource 检查内存日志源是否与其他日志源重名
+func (lv *LogViewer) checkMemorySource() error {
+	if lv.config.Memory == nil {
+		return nil
+	}
+	name := lv.memorySourceName()
+	for _, s := range lv.Sources() {
+		if s.memory == nil && s.config.Name == name {
+			return fmt.Errorf("%w: memory source name %q is already used by another source, set Config.MemorySourceName", ErrDuplicateSource, name)
+		}
+	}
+	return nil
+}
+
+// memoryPath 内存缓冲区在分页索引缓存中的键
+func (s *Source) memoryPath() string {
+	return "memory://" + s.config.Name + "/" + MemoryFileName
+}
+
+// openMemory 检查权限后返回缓冲区的快照
+func (s *Source) openMemory(perm, name string) (*logFile, string, error) {
+	if _, err := s.checkName(perm, name); err != nil {
+		return nil, "", err
+	}
+	return &logFile{logHandle: s.memory.snapshot(), memory: true}, s.memoryPath(), nil
+}
+
+// tailMemory 跟踪缓冲区中新写入的记录，缓冲区被清空时发送 truncate 事件
+func (s *Source) tailMemory(ctx context.Context, filename string, filter *Filter, fn func(TailEvent) error) error {
+	if _, err := s.checkName(PermTail, filename); err != nil {
+		return err
+	}
+	parser := builtinParsers[FormatJSON]
+	_, next, resets, changed := s.memory.since(^uint64(0))
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+		}
+
+		var records [][]byte
+		var current uint64
+		records, next, current, changed = s.memory.since(next)
+		if current != resets {
+			resets = current
+			if err := fn(TailEvent{Type: TailTruncate}); err != nil {
+				return err
+			}
+		}
+		for _, record := range records {
+			if _, err := emitLines(record, parser, filter, fn); err != nil {
+				return err
+			}
+		}
+	}
+}
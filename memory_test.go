@@ -0,0 +1,222 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/18 03:20:44
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/18 03:20:44
+ * Description: 内存环形缓冲区测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRingBuffer_Limits(t *testing.T) {
+	// 按条数限制
+	buf := NewRingBuffer(RingBufferOptions{MaxRecords: 3})
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(buf, "{\"msg\":\"%d\"}\n", i)
+	}
+	if got := string(buf.snapshot().content()); got != "{\"msg\":\"97\"}\n{\"msg\":\"98\"}\n{\"msg\":\"99\"}\n" {
+		t.Errorf("Unexpected records: %q", got)
+	}
+
+	// 按字节数限制，超过上限的单条记录不保存
+	buf = NewRingBuffer(RingBufferOptions{MaxBytes: 30})
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(buf, "{\"msg\":\"%d\"}\n", i) // 12 字节
+	}
+	if buf.Len() != 2 || buf.Size() != 24 {
+		t.Errorf("Expected 2 records of 24 bytes, got %d records of %d bytes", buf.Len(), buf.Size())
+	}
+	buf.Write([]byte(strings.Repeat("x", 31)))
+	if buf.Len() != 2 || buf.Dropped() != 1 {
+		t.Errorf("Expected oversized record to be dropped, got %d records, %d dropped", buf.Len(), buf.Dropped())
+	}
+
+	buf.Reset()
+	if buf.Len() != 0 || buf.Size() != 0 {
+		t.Errorf("Expected empty buffer after reset, got %d records", buf.Len())
+	}
+}
+
+// content 读取快照的全部内容
+func (f *memoryFile) content() []byte {
+	data := make([]byte, f.Size())
+	f.ReadAt(data, 0)
+	return data
+}
+
+func TestRingHandler(t *testing.T) {
+	buf := NewRingBuffer(RingBufferOptions{})
+	var next bytes.Buffer
+	logger := slog.New(NewRingHandler(slog.NewTextHandler(&next, nil), buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	logger.Debug("only in buffer")
+	logger.With("request_id", "r1").WithGroup("http").Info("request", "status", 500)
+
+	if strings.Contains(next.String(), "only in buffer") || !strings.Contains(next.String(), "http.status=500") {
+		t.Errorf("Unexpected output of next handler: %s", next.String())
+	}
+	lines := strings.Split(strings.TrimSpace(string(buf.snapshot().content())), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 records in buffer, got %d", len(lines))
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["request_id"] != "r1" || record["http"].(map[string]interface{})["status"] != float64(500) {
+		t.Errorf("Unexpected record: %v", record)
+	}
+}
+
+func TestMemorySource(t *testing.T) {
+	buf := NewRingBuffer(RingBufferOptions{MaxRecords: 100})
+	logger := slog.New(NewRingHandler(nil, buf, nil))
+	for i := 0; i < 5; i++ {
+		logger.Info("hello", "n", i)
+	}
+	logger.Error("failed", "n", 5)
+
+	// 只配置 Memory 时内存日志源为默认日志源
+	lv := New(&Config{Memory: buf, EnableExport: true, EnableClear: true, DevMode: true, PageSize: 2})
+	files, err := lv.ListLogFiles(ListOptions{Stats: true})
+	if err != nil || len(files) != 1 || files[0].Name != MemoryFileName || *files[0].Entries != 6 || files[0].Levels["ERROR"] != 1 {
+		t.Fatalf("Unexpected files: %+v %v", files, err)
+	}
+	if files[0].Size != buf.Size() || files[0].UncompressedSize != buf.Size() {
+		t.Errorf("Unexpected size: %+v", files[0])
+	}
+
+	errorLevel := slog.LevelError
+	logs, err := lv.GetLogContent(MemoryFileName, &Filter{MinLevel: &errorLevel})
+	if err != nil || len(logs) != 1 || logs[0].Msg != "failed" {
+		t.Errorf("Unexpected content: %+v %v", logs, err)
+	}
+	if _, err := lv.GetLogContent("app.log"); err == nil {
+		t.Error("Expected other files to be rejected")
+	}
+
+	// 新写入的记录使分页索引失效
+	page, err := lv.GetLogPage(MemoryFileName, PageQuery{Page: 1})
+	if err != nil || page.Total != 6 {
+		t.Fatalf("Unexpected page: %+v %v", page, err)
+	}
+	logger.Info("later")
+	if page, err = lv.GetLogPage(MemoryFileName, PageQuery{Page: 1}); err != nil || page.Total != 7 {
+		t.Errorf("Expected index to be rebuilt, got %+v %v", page, err)
+	}
+
+	handler := lv.Handler("/log")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/log/exportFile?name="+MemoryFileName+"&format=ndjson&level=ERROR", nil))
+	if w.Code != http.StatusOK || strings.Count(w.Body.String(), "\n") != 1 {
+		t.Errorf("Unexpected export: %d %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/log/exportFile?name="+MemoryFileName+"&format=text", nil))
+	if w.Code != http.StatusOK || int64(w.Body.Len()) != buf.Size() {
+		t.Errorf("Unexpected download: %d %d bytes", w.Code, w.Body.Len())
+	}
+
+	if err := lv.ClearFileContent(MemoryFileName); err != nil || buf.Len() != 0 {
+		t.Errorf("Expected buffer to be cleared: %v", err)
+	}
+}
+
+func TestMemorySource_WithLogDir(t *testing.T) {
+	tempDir := t.TempDir()
+	appendLine(t, filepath.Join(tempDir, "app.log"), `{"level":"INFO","msg":"disk"}`+"\n")
+	lv := New(&Config{LogDir: tempDir, Memory: NewRingBuffer(RingBufferOptions{})})
+
+	sources := lv.Sources()
+	if len(sources) != 2 || sources[0].Name() != DefaultSourceName || sources[1].Name() != MemorySourceName {
+		t.Fatalf("Unexpected sources: %v", sources)
+	}
+	files, err := sources[1].GetLogFiles()
+	if err != nil || len(files) != 1 || files[0] != MemoryFileName {
+		t.Errorf("Unexpected memory files: %v %v", files, err)
+	}
+
+	w := httptest.NewRecorder()
+	lv.Handler("/log").ServeHTTP(w, httptest.NewRequest("GET", "/log/getLogFilesList?source=memory", nil))
+	if !strings.Contains(w.Body.String(), MemoryFileName) {
+		t.Errorf("Unexpected file list: %s", w.Body.String())
+	}
+}
+
+func TestMemorySource_Name(t *testing.T) {
+	buf := NewRingBuffer(RingBufferOptions{})
+	sources := []SourceConfig{{Name: "memory", Dir: t.TempDir()}}
+
+	// 与配置的日志源重名时 NewWithError 返回错误，按名称获取日志源同样失败
+	if _, err := NewWithError(&Config{Sources: sources, Memory: buf}); !errors.Is(err, ErrDuplicateSource) {
+		t.Errorf("Expected ErrDuplicateSource, got %v", err)
+	}
+	if _, err := New(&Config{Sources: sources, Memory: buf}).Source("memory"); !errors.Is(err, ErrDuplicateSource) {
+		t.Errorf("Expected ErrDuplicateSource from Source, got %v", err)
+	}
+
+	// 指定名称后两个日志源都可以访问
+	lv, err := NewWithError(&Config{Sources: sources, Memory: buf, MemorySourceName: "ring"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if s, err := lv.Source("ring"); err != nil || s.memory == nil {
+		t.Errorf("Expected memory source named ring: %v %v", s, err)
+	}
+	if s, err := lv.Source("memory"); err != nil || s.memory != nil {
+		t.Errorf("Expected configured source named memory: %v %v", s, err)
+	}
+}
+
+func TestMemorySource_Tail(t *testing.T) {
+	buf := NewRingBuffer(RingBufferOptions{})
+	logger := slog.New(NewRingHandler(nil, buf, nil))
+	logger.Info("old keep")
+	lv := New(&Config{Memory: buf})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan TailEvent, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- lv.Tail(ctx, MemoryFileName, &Filter{Contains: "keep"}, func(event TailEvent) error {
+			events <- event
+			return nil
+		})
+	}()
+	time.Sleep(30 * time.Millisecond)
+
+	logger.Info("drop me")
+	logger.Info("keep 1")
+	if event := nextEvent(t, events); event.Type != TailEntry || event.Entry.Msg != "keep 1" {
+		t.Fatalf("Expected entry keep 1, got %+v", event)
+	}
+
+	buf.Reset()
+	if event := nextEvent(t, events); event.Type != TailTruncate {
+		t.Fatalf("Expected truncate event, got %+v", event)
+	}
+	logger.Info("keep 2")
+	if event := nextEvent(t, events); event.Entry == nil || event.Entry.Msg != "keep 2" {
+		t.Fatalf("Expected entry keep 2, got %+v", event)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
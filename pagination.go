@@ -0,0 +1,451 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 09:12:40
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 09:12:40
+ * Description: 日志分页读取
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultIndexCacheSize = 256 // 默认缓存分页索引的文件数
+	defaultPageSize       = 10
+	maxPageSize           = 1000
+	// 每隔多少条记录保存一次偏移量
+	checkpointInterval = 1000
+)
+
+// ErrInvalidCursor 游标格式错误
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// errIndexBuildAborted 索引构建未正常结束
+var errIndexBuildAborted = errors.New("index build aborted")
+
+// ErrInvalidPage 页码过大，起始记录序号超出 int 范围
+var ErrInvalidPage = errors.New("invalid page")
+
+// PageQuery 分页查询参数
+type PageQuery struct {
+	Page     int    // 页码，从1开始
+	PageSize int    // 每页条数，为0时使用 Config.PageSize
+	Cursor   string // 游标，非空时忽略 Page，从游标位置继续读取
+	Filter   *Filter
+}
+
+// LogPage 分页查询结果
+type LogPage struct {
+	Entries    []LogEntry `json:"data"`
+	Total      int        `json:"total"`
+	Page       int        `json:"page"`
+	PageSize   int        `json:"pageSize"`
+	NextCursor string     `json:"next"` // 下一页游标，为空表示已到末尾
+}
+
+// fileIndex 文件的稀疏偏移索引，用于跳转到指定页
+type fileIndex struct {
+	size    int64
+	modTime time.Time
+	end     int64   // 最后一个完整行之后的偏移
+	length  int64   // 内容长度，压缩文件为解压后的长度
+	count   int     // end 之前的记录数
+	total   int     // 记录总数（包含末尾未写完的行）
+	offsets []int64 // 第 i*checkpointInterval 条记录的起始偏移
+
+	levels       map[string]int // end 之前各级别的记录数
+	partialLevel string         // 末尾未写完的行的级别
+}
+
+// levelCounts 返回各级别的记录数
+func (idx *fileIndex) levelCounts() map[string]int {
+	counts := make(map[string]int, len(idx.levels)+1)
+	for level, n := range idx.levels {
+		counts[level] = n
+	}
+	if idx.total > idx.count {
+		counts[idx.partialLevel]++
+	}
+	return counts
+}
+
+// GetLogPage 分页获取日志内容，只读取目标窗口内的记录
+func (s *Source) GetLogPage(filename string, q PageQuery) (*LogPage, error) {
+	file, path, err := s.open(PermView, filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	parser := s.lv.fileParser(filename, file)
+	idx, err := s.lv.fileIndex(path, file, parser)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &LogPage{
+		Entries:  []LogEntry{},
+		Total:    idx.total,
+		Page:     q.Page,
+		PageSize: s.lv.pageSize(q.PageSize),
+	}
+	if page.Page < 1 {
+		page.Page = 1
+	}
+	if page.Page > math.MaxInt/page.PageSize {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidPage, q.Page)
+	}
+
+	if !q.Filter.Empty() {
+		return page, s.filterPage(file, path, parser, q, page)
+	}
+
+	// 计算起始偏移及需要跳过的记录数
+	var start int64
+	skip := 0
+	if q.Cursor != "" {
+		if start, err = decodeCursor(q.Cursor); err != nil {
+			return nil, err
+		}
+		page.Page = 0
+	} else {
+		first := (page.Page - 1) * page.PageSize
+		if first >= idx.total {
+			return page, nil
+		}
+		if checkpoint := first / checkpointInterval; checkpoint >= 0 && checkpoint < len(idx.offsets) {
+			start = idx.offsets[checkpoint]
+			skip = first - checkpoint*checkpointInterval
+		} else {
+			start = idx.end
+			skip = first - idx.count
+		}
+	}
+
+	r, err := file.reader(start)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	err = readLines(r, start, func(line []byte, _, end int64) bool {
+		entry, ok := parser.Parse(line)
+		if !ok {
+			return true
+		}
+		if skip > 0 {
+			skip--
+			return true
+		}
+		page.Entries = append(page.Entries, entry)
+		if len(page.Entries) < page.PageSize {
+			return true
+		}
+		if end < idx.length {
+			page.NextCursor = encodeCursor(end)
+		}
+		return false
+	})
+	return page, err
+}
+
+// filterPage 边读取边过滤，统计匹配总数并收集目标窗口内的记录，有持久索引时只读取候选记录
+func (s *Source) filterPage(file *logFile, path string, parser LineParser, q PageQuery, page *LogPage) error {
+	var cursor int64
+	first := (page.Page - 1) * page.PageSize
+	if q.Cursor != "" {
+		var err error
+		if cursor, err = decodeCursor(q.Cursor); err != nil {
+			return err
+		}
+		page.Page = 0
+		first = -1
+	}
+
+	var last int64
+	page.Total = 0
+	return s.scanFiltered(context.Background(), file, path, parser, q.Filter, 0, func(entry *LogEntry, start, end int64) bool {
+		index := page.Total
+		page.Total++
+		if start < cursor || (first >= 0 && index < first) {
+			return true
+		}
+		if len(page.Entries) < page.PageSize {
+			page.Entries = append(page.Entries, *entry)
+			last = end
+		} else if page.NextCursor == "" {
+			page.NextCursor = encodeCursor(last)
+		}
+		return true
+	})
+}
+
+// pageSize 返回有效的每页条数
+func (lv *LogViewer) pageSize(size int) int {
+	if size <= 0 {
+		size = lv.config.PageSize
+	}
+	if size <= 0 {
+		size = defaultPageSize
+	}
+	if size > maxPageSize {
+		size = maxPageSize
+	}
+	return size
+}
+
+// fileIndex 获取文件索引，文件追加时增量更新，截断或替换时重建。
+// 索引在 indexMu 之外构建，同一文件的并发请求等待同一次构建
+func (lv *LogViewer) fileIndex(path string, file *logFile, parser LineParser) (*fileIndex, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		lv.indexMu.Lock()
+		old := lv.indexes.get(path)
+		if old.fresh(info) {
+			lv.indexMu.Unlock()
+			return old, nil
+		}
+		if b := lv.indexBuilds[path]; b != nil {
+			lv.indexMu.Unlock()
+			<-b.done
+			if b.err == nil && b.idx.fresh(info) {
+				return b.idx, nil
+			}
+			// 构建失败或文件在构建后又有变化，重新检查
+			continue
+		}
+		b := &indexBuild{done: make(chan struct{})}
+		lv.indexBuilds[path] = b
+		// 文件已变化，旧索引只作为增量更新的起点，重建失败时不再保留
+		lv.indexes.remove(path)
+		lv.indexMu.Unlock()
+
+		lv.runIndexBuild(path, b, func() (*fileIndex, error) {
+			return lv.buildIndex(path, file, info, parser, old)
+		})
+		return b.idx, b.err
+	}
+}
+
+// indexBuild 正在构建的文件索引，构建完成后关闭 done
+type indexBuild struct {
+	done chan struct{}
+	idx  *fileIndex
+	err  error
+}
+
+// runIndexBuild 执行构建并保存结果，构建中 panic 时也会唤醒等待者
+func (lv *LogViewer) runIndexBuild(path string, b *indexBuild, build func() (*fileIndex, error)) {
+	b.err = errIndexBuildAborted
+	defer func() {
+		lv.indexMu.Lock()
+		delete(lv.indexBuilds, path)
+		if b.err == nil {
+			lv.indexes.put(path, b.idx)
+		}
+		lv.indexMu.Unlock()
+		close(b.done)
+	}()
+	b.idx, b.err = build()
+}
+
+// fresh 判断索引是否与文件当前的大小和修改时间一致
+func (idx *fileIndex) fresh(info fs.FileInfo) bool {
+	return idx != nil && idx.size == info.Size() && idx.modTime.Equal(info.ModTime())
+}
+
+// buildIndex 扫描文件生成索引，old 不为空且文件只追加了内容时从 old 继续
+func (lv *LogViewer) buildIndex(path string, file *logFile, info fs.FileInfo, parser LineParser, old *fileIndex) (*fileIndex, error) {
+	if old == nil && !file.memory {
+		// 进程重启后从持久索引继续，压缩文件的持久索引可直接使用
+		if ix := lv.persistentIndex(); ix != nil {
+			old = ix.pageIndex(path, file, info)
+		}
+		if old.fresh(info) {
+			return old, nil
+		}
+	}
+
+	idx := &fileIndex{size: info.Size(), modTime: info.ModTime(), levels: make(map[string]int)}
+	if old != nil && file.compression == "" && !file.memory && old.size <= info.Size() && file.complete(old.end) {
+		// 文件只追加了内容，从上次的完整行之后继续
+		idx.end = old.end
+		idx.count = old.count
+		idx.offsets = append([]int64(nil), old.offsets...)
+		for level, n := range old.levels {
+			idx.levels[level] = n
+		}
+	}
+
+	r, err := file.reader(idx.end)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	if file.compression == "" {
+		// 只读取到 Stat 时的大小，之后追加的内容留给下次增量更新
+		r = io.NopCloser(io.LimitReader(r, idx.size-idx.end))
+	}
+
+	partial := 0
+	idx.length = idx.end
+	err = readLines(r, idx.end, func(line []byte, start, end int64) bool {
+		entry, ok := parser.Parse(line)
+		idx.length = end
+		if file.compression == "" && end == idx.size && !file.complete(end) {
+			// 最后一行尚未写完，计入总数但不推进索引
+			if ok {
+				partial = 1
+				idx.partialLevel = levelName(entry.Level)
+			}
+			return false
+		}
+		if ok {
+			if idx.count%checkpointInterval == 0 {
+				idx.offsets = append(idx.offsets, start)
+			}
+			idx.count++
+			idx.levels[levelName(entry.Level)]++
+		}
+		idx.end = end
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if file.compression == "" {
+		idx.length = idx.size
+	}
+	idx.total = idx.count + partial
+	return idx, nil
+}
+
+// forgetIndex 删除文件的分页索引缓存，用于文件被删除或不存在时
+func (lv *LogViewer) forgetIndex(path string) {
+	lv.indexMu.Lock()
+	lv.indexes.remove(path)
+	lv.indexMu.Unlock()
+}
+
+// indexCache 分页索引的 LRU 缓存，超过容量时丢弃最久未使用的文件的索引。调用方需持有 indexMu
+type indexCache struct {
+	max   int
+	order *list.List // 最近使用的在前，元素为 *indexCacheEntry
+	items map[string]*list.Element
+}
+
+type indexCacheEntry struct {
+	path string
+	idx  *fileIndex
+}
+
+func newIndexCache(max int) *indexCache {
+	if max <= 0 {
+		max = defaultIndexCacheSize
+	}
+	return &indexCache{max: max, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+// get 返回文件的索引并标记为最近使用
+func (c *indexCache) get(path string) *fileIndex {
+	if e, ok := c.items[path]; ok {
+		c.order.MoveToFront(e)
+		return e.Value.(*indexCacheEntry).idx
+	}
+	return nil
+}
+
+// put 保存文件的索引
+func (c *indexCache) put(path string, idx *fileIndex) {
+	if e, ok := c.items[path]; ok {
+		e.Value.(*indexCacheEntry).idx = idx
+		c.order.MoveToFront(e)
+		return
+	}
+	c.items[path] = c.order.PushFront(&indexCacheEntry{path: path, idx: idx})
+	for c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*indexCacheEntry).path)
+	}
+}
+
+// remove 删除文件的索引
+func (c *indexCache) remove(path string) {
+	if e, ok := c.items[path]; ok {
+		c.order.Remove(e)
+		delete(c.items, path)
+	}
+}
+
+// len 返回缓存的文件数
+func (c *indexCache) len() int {
+	return c.order.Len()
+}
+
+// complete 判断 end 之前的内容是否以换行符结尾
+func complete(file io.ReaderAt, end int64) bool {
+	var b [1]byte
+	if end == 0 {
+		return true
+	}
+	if _, err := file.ReadAt(b[:], end-1); err != nil {
+		return false
+	}
+	return b[0] == '\n'
+}
+
+// readLines 从 offset 处开始逐行读取，回调参数为去除换行符的行内容及其起止偏移，
+// 回调返回 false 时停止读取
+func readLines(r io.Reader, offset int64, fn func(line []byte, start, end int64) bool) error {
+	reader := bufio.NewReaderSize(r, 64*1024)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			start := offset
+			offset += int64(len(line))
+			if !fn(bytes.TrimRight(line, "\r\n"), start, offset) {
+				return nil
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func encodeCursor(offset int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(offset, 10)))
+}
+
+func decodeCursor(cursor string) (int64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	offset, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidCursor, cursor)
+	}
+	return offset, nil
+}
@@ -0,0 +1,267 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 09:48:05
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 09:48:05
+ * Description: 分页测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeEntries 写入 n 条日志，消息为 "msg-<序号>"
+func writeEntries(t *testing.T, path string, from, n int) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open test file: %v", err)
+	}
+	defer file.Close()
+
+	var b strings.Builder
+	for i := from; i < from+n; i++ {
+		fmt.Fprintf(&b, `{"time":"2023-01-01T00:00:00Z","level":"INFO","msg":"msg-%d"}`+"\n", i)
+		if i%7 == 0 {
+			b.WriteString("not json\n")
+		}
+	}
+	if _, err := file.WriteString(b.String()); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+}
+
+func TestGetLogPage(t *testing.T) {
+	tempDir := t.TempDir()
+	lv := New(&Config{LogDir: tempDir, PageSize: 20})
+	writeEntries(t, filepath.Join(tempDir, "app.log"), 0, 2500)
+
+	tests := []struct {
+		name      string
+		query     PageQuery
+		wantFirst string
+		wantLen   int
+	}{
+		{"First page uses config size", PageQuery{Page: 1}, "msg-0", 20},
+		{"Page inside first checkpoint", PageQuery{Page: 3, PageSize: 10}, "msg-20", 10},
+		{"Page after checkpoint", PageQuery{Page: 151, PageSize: 10}, "msg-1500", 10},
+		{"Last partial page", PageQuery{Page: 9, PageSize: 300}, "msg-2400", 100},
+		{"Beyond end", PageQuery{Page: 100, PageSize: 300}, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := lv.GetLogPage("app.log", tt.query)
+			if err != nil {
+				t.Fatalf("GetLogPage failed: %v", err)
+			}
+			if page.Total != 2500 {
+				t.Errorf("Expected total 2500, got %d", page.Total)
+			}
+			if len(page.Entries) != tt.wantLen {
+				t.Fatalf("Expected %d entries, got %d", tt.wantLen, len(page.Entries))
+			}
+			if tt.wantLen > 0 && page.Entries[0].Msg != tt.wantFirst {
+				t.Errorf("Expected first entry %s, got %s", tt.wantFirst, page.Entries[0].Msg)
+			}
+		})
+	}
+}
+
+func TestGetLogPage_Cursor(t *testing.T) {
+	tempDir := t.TempDir()
+	lv := New(&Config{LogDir: tempDir})
+	writeEntries(t, filepath.Join(tempDir, "app.log"), 0, 25)
+
+	var msgs []string
+	query := PageQuery{PageSize: 10}
+	for {
+		page, err := lv.GetLogPage("app.log", query)
+		if err != nil {
+			t.Fatalf("GetLogPage failed: %v", err)
+		}
+		for _, entry := range page.Entries {
+			msgs = append(msgs, entry.Msg)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	if len(msgs) != 25 || msgs[24] != "msg-24" {
+		t.Errorf("Expected 25 entries in order, got %d", len(msgs))
+	}
+
+	if _, err := lv.GetLogPage("app.log", PageQuery{Cursor: "!!"}); err == nil {
+		t.Error("Expected error for invalid cursor")
+	}
+}
+
+func TestGetLogPage_AppendAndTruncate(t *testing.T) {
+	tempDir := t.TempDir()
+	lv := New(&Config{LogDir: tempDir})
+	path := filepath.Join(tempDir, "app.log")
+	writeEntries(t, path, 0, 1200)
+
+	if page, _ := lv.GetLogPage("app.log", PageQuery{}); page.Total != 1200 {
+		t.Fatalf("Expected total 1200, got %d", page.Total)
+	}
+
+	// 追加内容后增量更新
+	writeEntries(t, path, 1200, 1000)
+	page, err := lv.GetLogPage("app.log", PageQuery{Page: 220, PageSize: 10})
+	if err != nil {
+		t.Fatalf("GetLogPage failed: %v", err)
+	}
+	if page.Total != 2200 || page.Entries[0].Msg != "msg-2190" {
+		t.Errorf("Unexpected page after append: total %d, first %s", page.Total, page.Entries[0].Msg)
+	}
+
+	// 截断后重建
+	os.WriteFile(path, nil, 0644)
+	writeEntries(t, path, 0, 3)
+	page, err = lv.GetLogPage("app.log", PageQuery{})
+	if err != nil {
+		t.Fatalf("GetLogPage failed: %v", err)
+	}
+	if page.Total != 3 {
+		t.Errorf("Expected total 3 after truncation, got %d", page.Total)
+	}
+}
+
+func TestIndexCache(t *testing.T) {
+	tempDir := t.TempDir()
+	lv := New(&Config{LogDir: tempDir, IndexCacheSize: 2})
+	for _, name := range []string{"a.log", "b.log", "c.log"} {
+		writeEntries(t, filepath.Join(tempDir, name), 0, 3)
+	}
+	path := func(name string) string { return filepath.Join(tempDir, name) }
+
+	// 超过容量时丢弃最久未使用的索引
+	lv.GetLogPage("a.log", PageQuery{})
+	lv.GetLogPage("b.log", PageQuery{})
+	lv.GetLogPage("a.log", PageQuery{})
+	lv.GetLogPage("c.log", PageQuery{})
+	if lv.indexes.len() != 2 || lv.indexes.get(path("b.log")) != nil || lv.indexes.get(path("a.log")) == nil {
+		t.Errorf("Expected b.log to be evicted, got %d entries", lv.indexes.len())
+	}
+
+	// 删除的文件及不存在的文件不再保留索引
+	if err := lv.defaultSource().removeFile(PermView, "a.log"); err != nil {
+		t.Fatalf("removeFile failed: %v", err)
+	}
+	os.Remove(path("c.log"))
+	if _, err := lv.GetLogPage("c.log", PageQuery{}); err == nil {
+		t.Fatal("Expected error for removed file")
+	}
+	if lv.indexes.len() != 0 {
+		t.Errorf("Expected indexes of removed files to be evicted, got %d entries", lv.indexes.len())
+	}
+}
+
+func TestFileIndex_BuildOutsideLock(t *testing.T) {
+	tempDir := t.TempDir()
+	lv := New(&Config{LogDir: tempDir})
+	writeEntries(t, filepath.Join(tempDir, "a.log"), 0, 3)
+	writeEntries(t, filepath.Join(tempDir, "b.log"), 0, 5)
+
+	// 模拟 a.log 的索引正在构建
+	path := filepath.Join(tempDir, "a.log")
+	release := make(chan struct{})
+	b := &indexBuild{done: make(chan struct{})}
+	lv.indexMu.Lock()
+	lv.indexBuilds[path] = b
+	lv.indexMu.Unlock()
+	go lv.runIndexBuild(path, b, func() (*fileIndex, error) {
+		<-release
+		return nil, errors.New("build failed")
+	})
+
+	// 其他文件不受影响
+	page, err := lv.GetLogPage("b.log", PageQuery{})
+	if err != nil || page.Total != 5 {
+		t.Fatalf("Expected b.log to be indexed while a.log is building, got %v, %v", page, err)
+	}
+
+	// 同一文件等待正在进行的构建，失败后重新构建
+	result := make(chan int, 1)
+	go func() {
+		page, err := lv.GetLogPage("a.log", PageQuery{})
+		if err != nil {
+			result <- -1
+			return
+		}
+		result <- page.Total
+	}()
+	select {
+	case total := <-result:
+		t.Fatalf("Expected a.log to wait for the running build, got total %d", total)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	if total := <-result; total != 3 {
+		t.Errorf("Expected total 3 after the failed build, got %d", total)
+	}
+	if len(lv.indexBuilds) != 0 {
+		t.Errorf("Expected no builds in progress, got %d", len(lv.indexBuilds))
+	}
+}
+
+func TestGetContentHandler_Pagination(t *testing.T) {
+	tempDir := t.TempDir()
+	lv := New(&Config{LogDir: tempDir})
+	writeEntries(t, filepath.Join(tempDir, "app.log"), 0, 35)
+
+	req := httptest.NewRequest("GET", "/log/getFileContent?name=app.log&page=4", nil)
+	w := httptest.NewRecorder()
+
+	lv.GetContentHandler(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status OK, got %v", resp.Status)
+	}
+
+	var response struct {
+		Code     int        `json:"code"`
+		Data     []LogEntry `json:"data"`
+		Total    int        `json:"total"`
+		PageSize int        `json:"pageSize"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Total != 35 || response.PageSize != defaultPageSize || len(response.Data) != 5 {
+		t.Errorf("Unexpected response: total %d, pageSize %d, entries %d", response.Total, response.PageSize, len(response.Data))
+	}
+
+	// 页码过大时 (page-1)*pageSize 会溢出，应返回 400 而不是越界
+	// 页码不是正整数时同样返回 400，而不是按第一页处理
+	for _, query := range []string{"page=1000000000000000000&pageSize=10", "page=1000000000000000000&pageSize=10&level=INFO",
+		"page=abc", "page=-3", "page=0", "pageSize=x", "pageSize=-1"} {
+		w := httptest.NewRecorder()
+		lv.GetContentHandler(w, httptest.NewRequest("GET", "/log/getFileContent?name=app.log&"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status BadRequest for huge page, got %d", query, w.Code)
+		}
+	}
+
+	req = httptest.NewRequest("GET", "/log/getFileContent?name=app.log&cursor=bad*", nil)
+	w = httptest.NewRecorder()
+	lv.GetContentHandler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status BadRequest for invalid cursor, got %d", w.Code)
+	}
+}
@@ -0,0 +1,297 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 13:05:52
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 13:05:52
+ * Description: 日志行解析器
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"bytes"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// 日志格式
+const (
+	FormatAuto  = "auto"  // 根据文件内容自动识别
+	FormatJSON  = "json"  // slog.JSONHandler
+	FormatText  = "text"  // slog.TextHandler 及 logfmt
+	FormatPlain = "plain" // 纯文本，整行作为消息
+)
+
+// 自动识别格式时采样的字节数和行数
+const (
+	sampleSize  = 16 * 1024
+	sampleLines = 20
+)
+
+// LineParser 日志行解析器，将一行日志转换为 LogEntry，无法解析时返回 false
+type LineParser interface {
+	Parse(line []byte) (LogEntry, bool)
+}
+
+// JSONParser 解析 slog.JSONHandler 输出
+type JSONParser struct{}
+
+func (JSONParser) Parse(line []byte) (LogEntry, bool) {
+	return parseJSONEntry(line)
+}
+
+// TextParser 解析 slog.TextHandler 及 logfmt 格式（key=value），
+// 带 "." 的键按 slog 分组还原为嵌套结构
+type TextParser struct{}
+
+func (TextParser) Parse(line []byte) (LogEntry, bool) {
+	fields, ok := parseLogfmt(line)
+	if !ok {
+		return LogEntry{}, false
+	}
+
+	attrs := make(map[string]interface{}, len(fields))
+	var entry LogEntry
+	for _, f := range fields {
+		switch f.key {
+		case levelKey:
+			entry.Level = f.value
+		case timeKey:
+			entry.Time = f.value
+		case msgKey:
+			entry.Msg = f.value
+		default:
+			setAttr(attrs, f.key, f.value)
+		}
+	}
+	if len(attrs) > 0 {
+		entry.Attrs = attrs
+	}
+	return entry, true
+}
+
+// PlainParser 将整行作为消息，用于无法识别格式的文件
+type PlainParser struct{}
+
+func (PlainParser) Parse(line []byte) (LogEntry, bool) {
+	if len(bytes.TrimSpace(line)) == 0 {
+		return LogEntry{}, false
+	}
+	return LogEntry{Msg: string(line)}, true
+}
+
+var builtinParsers = map[string]LineParser{
+	FormatJSON:  JSONParser{},
+	FormatText:  TextParser{},
+	FormatPlain: PlainParser{},
+}
+
+// parser 返回文件使用的解析器：优先按 FileFormats 匹配文件名，其次使用 Format，
+// 自动识别时根据 sample 判断
+func (lv *LogViewer) parser(filename string, sample []byte) LineParser {
+	format := lv.config.Format
+	if pattern, ok := mostSpecificMatch(lv.config.FileFormats, filename); ok {
+		format = lv.config.FileFormats[pattern]
+	}
+
+	if p, ok := lv.config.Parsers[format]; ok {
+		return p
+	}
+	if p, ok := builtinParsers[format]; ok {
+		return p
+	}
+	return detectParser(sample)
+}
+
+// fileParser 读取文件开头的样本，返回文件使用的解析器，内存缓冲区总是 JSON 格式
+func (lv *LogViewer) fileParser(filename string, file *logFile) LineParser {
+	if file.memory {
+		return builtinParsers[FormatJSON]
+	}
+	return lv.parser(filename, file.sample())
+}
+
+// readSample 读取文件开头用于识别格式的样本内容
+func readSample(file io.ReaderAt) []byte {
+	buf := make([]byte, sampleSize)
+	n, _ := file.ReadAt(buf, 0)
+	return buf[:n]
+}
+
+// mostSpecificMatch 返回匹配文件名的最具体的模式：匹配相对路径的模式优先，其次字面字符多的模式，
+// 仍相同时按模式字符串排序，保证多个模式同时匹配时结果固定
+func mostSpecificMatch(patterns map[string]string, name string) (string, bool) {
+	best, found := "", false
+	for pattern := range patterns {
+		if !matchName(pattern, name) {
+			continue
+		}
+		if !found || moreSpecific(pattern, best) {
+			best, found = pattern, true
+		}
+	}
+	return best, found
+}
+
+// moreSpecific 判断模式 a 是否比 b 更具体
+func moreSpecific(a, b string) bool {
+	if sa, sb := strings.Contains(a, "/"), strings.Contains(b, "/"); sa != sb {
+		return sa
+	}
+	if la, lb := literalLength(a), literalLength(b); la != lb {
+		return la > lb
+	}
+	return a < b
+}
+
+// literalLength 返回模式中字面字符的数量，"*" 和 "?" 不计入，"[...]" 计为一个字符
+func literalLength(pattern string) int {
+	n := 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*', '?':
+		case '\\':
+			i++
+			n++
+		case '[':
+			if end := strings.IndexByte(pattern[i:], ']'); end > 0 {
+				i += end
+			}
+			n++
+		default:
+			n++
+		}
+	}
+	return n
+}
+
+// matchName 按通配符匹配文件名，模式不含 "/" 时只匹配最后一级
+func matchName(pattern, name string) bool {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if !strings.Contains(pattern, "/") {
+		name = path.Base(name)
+	}
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+// detectParser 根据样本内容识别日志格式，无法判断时默认 JSON
+func detectParser(sample []byte) LineParser {
+	var jsonLines, textLines, lines int
+	for len(sample) > 0 && lines < sampleLines {
+		i := bytes.IndexByte(sample, '\n')
+		if i < 0 {
+			break // 忽略不完整的最后一行
+		}
+		line := bytes.TrimSpace(sample[:i])
+		sample = sample[i+1:]
+		if len(line) == 0 {
+			continue
+		}
+		lines++
+		if _, ok := parseJSONEntry(line); ok {
+			jsonLines++
+		} else if fields, ok := parseLogfmt(line); ok && hasBuiltinKey(fields) {
+			textLines++
+		}
+	}
+
+	switch {
+	case lines == 0 || (jsonLines > 0 && jsonLines >= textLines):
+		return JSONParser{}
+	case textLines > 0:
+		return TextParser{}
+	default:
+		return PlainParser{}
+	}
+}
+
+func hasBuiltinKey(fields []logfmtField) bool {
+	for _, f := range fields {
+		if f.key == levelKey || f.key == msgKey || f.key == timeKey {
+			return true
+		}
+	}
+	return false
+}
+
+type logfmtField struct {
+	key   string
+	value string
+}
+
+// parseLogfmt 解析 key=value 序列，值可使用双引号并包含转义字符
+func parseLogfmt(line []byte) ([]logfmtField, bool) {
+	s := string(bytes.TrimSpace(line))
+	if s == "" {
+		return nil, false
+	}
+
+	var fields []logfmtField
+	for len(s) > 0 {
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 || strings.ContainsAny(s[:eq], " \t\"") {
+			return nil, false
+		}
+		key := s[:eq]
+		s = s[eq+1:]
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := closingQuote(s)
+			if end < 0 {
+				return nil, false
+			}
+			v, err := strconv.Unquote(s[:end+1])
+			if err != nil {
+				return nil, false
+			}
+			value, s = v, s[end+1:]
+		} else if sp := strings.IndexAny(s, " \t"); sp >= 0 {
+			value, s = s[:sp], s[sp:]
+		} else {
+			value, s = s, ""
+		}
+
+		if s != "" && s[0] != ' ' && s[0] != '\t' {
+			return nil, false
+		}
+		s = strings.TrimLeft(s, " \t")
+		fields = append(fields, logfmtField{key: key, value: value})
+	}
+	return fields, true
+}
+
+// closingQuote 返回与开头引号匹配的结束引号位置
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// setAttr 按 "." 拆分键名写入嵌套分组，与已有非分组值冲突时保留完整键名
+func setAttr(attrs map[string]interface{}, key string, value interface{}) {
+	parts := strings.Split(key, ".")
+	m := attrs
+	for _, part := range parts[:len(parts)-1] {
+		sub, ok := m[part].(map[string]interface{})
+		if !ok {
+			if _, exists := m[part]; exists {
+				attrs[key] = value
+				return
+			}
+			sub = make(map[string]interface{})
+			m[part] = sub
+		}
+		m = sub
+	}
+	m[parts[len(parts)-1]] = value
+}
@@ -0,0 +1,168 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 13:41:26
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 13:41:26
+ * Description: 解析器测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTextParser(t *testing.T) {
+	// 使用 slog.TextHandler 生成日志
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	logger.WithGroup("req").Info("request done", "method", "GET", "path", "/a b")
+	logger.Error(`bad "quote"`, "err", "x=y", "user_id", 42)
+
+	var entries []LogEntry
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		entry, ok := TextParser{}.Parse([]byte(line))
+		if !ok {
+			t.Fatalf("Failed to parse %q", line)
+		}
+		entries = append(entries, entry)
+	}
+
+	if entries[0].Level != "INFO" || entries[0].Msg != "request done" || entries[0].Time == "" {
+		t.Errorf("Unexpected entry: %+v", entries[0])
+	}
+	group, ok := entries[0].Attrs["req"].(map[string]interface{})
+	if !ok || group["method"] != "GET" || group["path"] != "/a b" {
+		t.Errorf("Expected nested group, got %v", entries[0].Attrs)
+	}
+	if entries[1].Msg != `bad "quote"` || entries[1].Attrs["err"] != "x=y" || entries[1].Attrs["user_id"] != "42" {
+		t.Errorf("Unexpected entry: %+v", entries[1])
+	}
+
+	for _, line := range []string{"plain text line", `msg="unterminated`, `a="x"b=1`, ""} {
+		if _, ok := (TextParser{}).Parse([]byte(line)); ok {
+			t.Errorf("Expected %q to be rejected", line)
+		}
+	}
+}
+
+func TestJSONParser_TrailingData(t *testing.T) {
+	for _, line := range []string{`{"msg":"a"} trailing junk`, `{"msg":"a"}{"msg":"b"}`, `{"msg":"a"} 1`} {
+		if _, ok := (JSONParser{}).Parse([]byte(line)); ok {
+			t.Errorf("Expected %q to be rejected", line)
+		}
+	}
+	if entry, ok := (JSONParser{}).Parse([]byte(`{"msg":"a"}  `)); !ok || entry.Msg != "a" {
+		t.Errorf("Expected trailing spaces to be accepted, got %+v %v", entry, ok)
+	}
+
+	// 带尾随内容的行不计为 JSON，格式识别不受误导
+	if _, ok := detectParser([]byte("{\"msg\":\"a\"} trailing junk\n")).(PlainParser); !ok {
+		t.Error("Expected lines with trailing data to be detected as plain text")
+	}
+}
+
+func TestGetLogContent_Formats(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"json.log":   `{"time":"2023-01-01T00:00:00Z","level":"INFO","msg":"json"}` + "\n",
+		"text.log":   `time=2023-01-01T00:00:00Z level=WARN msg="text line" k=v` + "\n",
+		"logfmt.log": `level=error msg=logfmt` + "\n",
+		"plain.log":  "panic: something\n\ngoroutine 1 [running]:\n",
+		"forced.txt": `level=INFO msg=forced` + "\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	lv := New(&Config{
+		LogDir:      tempDir,
+		FileFormats: map[string]string{"*.txt": FormatPlain},
+	})
+
+	tests := []struct {
+		file  string
+		count int
+		level string
+		msg   string
+	}{
+		{"json.log", 1, "INFO", "json"},
+		{"text.log", 1, "WARN", "text line"},
+		{"logfmt.log", 1, "error", "logfmt"},
+		{"plain.log", 2, "", "panic: something"},
+		{"forced.txt", 1, "", "level=INFO msg=forced"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			entries, err := lv.GetLogContent(tt.file)
+			if err != nil {
+				t.Fatalf("GetLogContent failed: %v", err)
+			}
+			if len(entries) != tt.count {
+				t.Fatalf("Expected %d entries, got %d", tt.count, len(entries))
+			}
+			if entries[0].Level != tt.level || entries[0].Msg != tt.msg {
+				t.Errorf("Unexpected entry: %+v", entries[0])
+			}
+		})
+	}
+}
+
+// upperParser 自定义解析器，将整行转为大写作为消息
+type upperParser struct{}
+
+func (upperParser) Parse(line []byte) (LogEntry, bool) {
+	return LogEntry{Level: "INFO", Msg: strings.ToUpper(string(line))}, true
+}
+
+func TestGetLogContent_CustomParser(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "app.log"), []byte("hello\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	lv := New(&Config{
+		LogDir:  tempDir,
+		Format:  "upper",
+		Parsers: map[string]LineParser{"upper": upperParser{}},
+	})
+
+	entries, err := lv.GetLogContent("app.log")
+	if err != nil {
+		t.Fatalf("GetLogContent failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Msg != "HELLO" {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+}
+
+func TestMostSpecificMatch(t *testing.T) {
+	patterns := map[string]string{
+		"*":             FormatJSON,
+		"*.log":         FormatJSON,
+		"worker-*.log":  FormatText,
+		"worker-1.log":  FormatPlain,
+		"jobs/*.log":    FormatText,
+		"worker-[0-9]*": FormatPlain,
+	}
+	tests := map[string]string{
+		"app.log":           "*.log",
+		"worker-2.log":      "worker-*.log",
+		"worker-1.log":      "worker-1.log",
+		"jobs/worker-1.log": "jobs/*.log",
+		"README":            "*",
+	}
+	// 多次匹配结果相同，不受 map 遍历顺序影响
+	for i := 0; i < 20; i++ {
+		for name, want := range tests {
+			if got, ok := mostSpecificMatch(patterns, name); !ok || got != want {
+				t.Fatalf("%s: expected %q, got %q", name, want, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,166 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 16:05:17
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 16:05:17
+ * Description: 日志文件路径校验，所有文件访问均经过 os.Root，不会越出日志目录
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidPath 文件名不合法或超出日志目录
+var ErrInvalidPath = errors.New("invalid filename")
+
+// cleanName 校验并规范化相对于日志目录的文件名，统一使用 "/" 分隔
+func cleanName(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, "\\\x00") || path.IsAbs(name) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("%w: %q", ErrInvalidPath, name)
+	}
+	clean := path.Clean(name)
+	if clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidPath, name)
+	}
+	return clean, nil
+}
+
+// checkName 检查当前用户对文件的 perm 权限，返回规范化的文件名，被通配符规则隐藏的文件视为不存在
+func (s *Source) checkName(perm, name string) (string, error) {
+	clean, err := cleanName(name)
+	if err != nil {
+		return "", err
+	}
+	if err := s.authorize(perm, clean); err != nil {
+		return "", err
+	}
+	if s.hidden(clean) {
+		return "", fmt.Errorf("%w: %q", fs.ErrNotExist, name)
+	}
+	return clean, nil
+}
+
+// resolvePath 检查当前用户对文件的 perm 权限，并将相对文件名解析为日志目录下的路径。
+// 文件通过 os.Root 检查：拒绝 ".." 等越界访问、指向日志目录之外的符号链接及目录、FIFO 等非普通文件
+func (s *Source) resolvePath(perm, name string) (string, error) {
+	clean, err := s.checkName(perm, name)
+	if err != nil {
+		return "", err
+	}
+	if s.memory != nil {
+		return s.memoryPath(), nil
+	}
+	if _, err := s.rootStat(name, clean); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.config.Dir, filepath.FromSlash(clean)), nil
+}
+
+// statFile 检查权限后通过 os.Root 获取文件信息，与 openFile 相同只接受日志目录中的普通文件
+func (s *Source) statFile(perm, name string) (fs.FileInfo, error) {
+	clean, err := s.checkName(perm, name)
+	if err != nil {
+		return nil, err
+	}
+	return s.rootStat(name, clean)
+}
+
+// rootStat 通过 os.Root 获取 checkName 规范化后的文件的信息，只接受普通文件
+func (s *Source) rootStat(name, clean string) (fs.FileInfo, error) {
+	root, err := os.OpenRoot(s.config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	info, err := root.Stat(filepath.FromSlash(clean))
+	if err := regularFile(name, info, err); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// openFile 检查权限后通过 os.Root 打开文件，返回文件及其路径。
+// 打开前检查文件类型，避免以写方式打开 FIFO 时阻塞；打开后再次检查，避免检查与打开之间文件被替换
+func (s *Source) openFile(perm, name string, flag int) (*os.File, string, error) {
+	clean, err := s.checkName(perm, name)
+	if err != nil {
+		return nil, "", err
+	}
+	root, err := os.OpenRoot(s.config.Dir)
+	if err != nil {
+		return nil, "", err
+	}
+	defer root.Close()
+
+	rel := filepath.FromSlash(clean)
+	info, err := root.Stat(rel)
+	if errors.Is(err, fs.ErrNotExist) {
+		s.lv.forgetIndex(filepath.Join(s.config.Dir, rel))
+	}
+	if err := regularFile(name, info, err); err != nil {
+		return nil, "", err
+	}
+	file, err := root.OpenFile(rel, flag, 0)
+	if err != nil {
+		return nil, "", regularFile(name, nil, err)
+	}
+	info, err = file.Stat()
+	if err := regularFile(name, info, err); err != nil {
+		file.Close()
+		return nil, "", err
+	}
+	return file, filepath.Join(s.config.Dir, rel), nil
+}
+
+// removeFile 检查权限后通过 os.Root 删除文件，与读取时的检查相同，只能删除普通文件。
+// 内存日志源的文件不能删除，改为清空缓冲区
+func (s *Source) removeFile(perm, name string) error {
+	clean, err := s.checkName(perm, name)
+	if err != nil {
+		return err
+	}
+	if s.memory != nil {
+		s.memory.Reset()
+		return nil
+	}
+	root, err := os.OpenRoot(s.config.Dir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	rel := filepath.FromSlash(clean)
+	info, err := root.Stat(rel)
+	if err := regularFile(name, info, err); err != nil {
+		return err
+	}
+	if err := root.Remove(rel); err != nil {
+		return err
+	}
+	s.lv.forgetIndex(filepath.Join(s.config.Dir, rel))
+	return nil
+}
+
+// regularFile 检查 os.Root 返回的文件是否为普通文件。文件不存在或没有系统权限时保留原错误，
+// 其余错误（如路径越出日志目录）均视为 ErrInvalidPath
+func regularFile(name string, info fs.FileInfo, err error) error {
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %q: %v", ErrInvalidPath, name, err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%w: %q is not a regular file", ErrInvalidPath, name)
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 23:41:08
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 23:41:08
+ * Description: 路径穿越测试，覆盖所有接收文件名的方法及接口
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const secretContent = `{"level":"INFO","msg":"TOP SECRET"}` + "\n"
+
+// newTraversalViewer 创建日志目录及其外部的 secret.log，日志目录中包含空的子目录及指向外部的符号链接
+func newTraversalViewer(t *testing.T, cfg *Config) (lv *LogViewer, secret string) {
+	t.Helper()
+	base := t.TempDir()
+	cfg.LogDir = filepath.Join(base, "logs")
+	lv = newTestViewer(t, map[string]string{"app.log": `{"level":"INFO","msg":"hello"}` + "\n"}, cfg)
+	outside := filepath.Join(base, "outside")
+	secret = filepath.Join(outside, "secret.log")
+	for _, dir := range []string{filepath.Join(cfg.LogDir, "sub"), outside} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	appendLine(t, secret, secretContent)
+
+	links := map[string]string{
+		"link.log":  filepath.Join("..", "outside", "secret.log"),
+		"abs.log":   secret,
+		"linkdir":   outside,
+		"alias.log": "app.log",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(cfg.LogDir, name)); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+	return lv, secret
+}
+
+// traversalNames 越出日志目录或不是普通文件的文件名
+func traversalNames(secret string) []string {
+	return []string{
+		"../outside/secret.log",
+		"sub/../../outside/secret.log",
+		"..%2Foutside%2Fsecret.log",
+		secret,
+		"..\\outside\\secret.log",
+		"link.log",
+		"abs.log",
+		"linkdir/secret.log",
+		"sub",
+		".",
+	}
+}
+
+// checkSecret 检查日志目录外的文件未被修改
+func checkSecret(t *testing.T, secret string) {
+	t.Helper()
+	content, err := os.ReadFile(secret)
+	if err != nil || string(content) != secretContent {
+		t.Fatalf("File outside LogDir was modified: %q %v", content, err)
+	}
+}
+
+func TestTraversal_Core(t *testing.T) {
+	lv, secret := newTraversalViewer(t, &Config{DevMode: true, EnableClear: true, EnableDelete: true, EnableExport: true})
+	s := lv.defaultSource()
+
+	for _, name := range traversalNames(secret) {
+		t.Run(name, func(t *testing.T) {
+			calls := map[string]func() error{
+				"GetLogContent":    func() error { _, err := s.GetLogContent(name); return err },
+				"GetLogPage":       func() error { _, err := s.GetLogPage(name, PageQuery{}); return err },
+				"ExportContent":    func() error { _, err := s.ExportContent(name); return err },
+				"ClearFileContent": func() error { return s.ClearFileContent(name) },
+				"ExportFile":       func() error { return s.ExportFile(name) },
+				"Tail": func() error {
+					ctx, cancel := context.WithCancel(context.Background())
+					cancel()
+					return s.Tail(ctx, name, nil, func(TailEvent) error { return nil })
+				},
+			}
+			for method, call := range calls {
+				if err := call(); !errors.Is(err, ErrInvalidPath) && !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("%s(%q) = %v, want ErrInvalidPath", method, name, err)
+				}
+			}
+			checkSecret(t, secret)
+		})
+	}
+
+	// 符号链接指向日志目录之外时无论是否解析都拒绝
+	for _, name := range []string{"link.log", "abs.log", "linkdir/secret.log", "sub"} {
+		if _, err := s.GetLogContent(name); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("GetLogContent(%q) = %v, want ErrInvalidPath", name, err)
+		}
+	}
+
+	// 指向日志目录内的符号链接可以正常读取
+	entries, err := s.GetLogContent("alias.log")
+	if err != nil || len(entries) != 1 || entries[0].Msg != "hello" {
+		t.Errorf("Expected symlink inside LogDir to be readable: %v %v", entries, err)
+	}
+
+	// 文件列表与读取的规则相同，只包含指向日志目录内的符号链接
+	files, err := s.ListLogFiles(ListOptions{Recursive: true})
+	if err != nil || len(files) != 2 || files[0].Name != "alias.log" || files[1].Name != "app.log" || files[0].Size != files[1].Size {
+		t.Errorf("Unexpected files: %+v %v", files, err)
+	}
+	if err := s.DeleteAllLogs(); err != nil {
+		t.Fatalf("DeleteAllLogs failed: %v", err)
+	}
+	checkSecret(t, secret)
+}
+
+func TestTraversal_TailRotation(t *testing.T) {
+	lv, secret := newTraversalViewer(t, &Config{TailInterval: 10 * time.Millisecond})
+	path := filepath.Join(lv.config.LogDir, "app.log")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan TailEvent, 16)
+	go lv.Tail(ctx, "app.log", nil, func(event TailEvent) error {
+		events <- event
+		return nil
+	})
+	time.Sleep(30 * time.Millisecond)
+
+	// 轮转后的文件名指向日志目录之外时不切换，继续跟踪原文件
+	os.Rename(path, path+".1")
+	if err := os.Symlink(secret, path); err != nil {
+		t.Fatal(err)
+	}
+	appendLine(t, path+".1", `{"level":"INFO","msg":"after"}`+"\n")
+	if event := nextEvent(t, events); event.Entry == nil || event.Entry.Msg != "after" {
+		t.Fatalf("Expected entry from the original file, got %+v", event)
+	}
+	select {
+	case event := <-events:
+		t.Errorf("Unexpected event after rotation to a symlink outside LogDir: %+v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestTraversal_Handlers(t *testing.T) {
+	lv, secret := newTraversalViewer(t, &Config{DevMode: true, EnableClear: true, EnableDelete: true, EnableExport: true})
+	handler := lv.Handler("/log")
+
+	for _, name := range traversalNames(secret) {
+		t.Run(name, func(t *testing.T) {
+			query := url.Values{"name": {name}}.Encode()
+			glob := url.Values{"glob": {name}, "recursive": {"true"}}.Encode()
+			// filtered 按文件名或通配符过滤的接口，没有匹配的文件时返回空结果
+			requests := []struct {
+				req      *http.Request
+				filtered bool
+			}{
+				{httptest.NewRequest("GET", "/log/getFileContent?"+query, nil), false},
+				{httptest.NewRequest("GET", "/log/getFileContent?cursor=&"+query, nil), false},
+				{httptest.NewRequest("GET", "/log/tail?"+query, nil), false},
+				{httptest.NewRequest("GET", "/log/exportFile?"+query, nil), false},
+				{httptest.NewRequest("GET", "/log/exportFile?format=text&"+query, nil), false},
+				{httptest.NewRequest("GET", "/log/exportFile?format=ndjson&"+query, nil), false},
+				{httptest.NewRequest("GET", "/log/exportFile?format=csv&"+query, nil), false},
+				{httptest.NewRequest("GET", "/log/exportFile?format=xlsx&"+query, nil), false},
+				{httptest.NewRequest("GET", "/log/exportBundle?"+query, nil), false},
+				{httptest.NewRequest("GET", "/log/exportBundle?"+glob, nil), false},
+				{httptest.NewRequest("GET", "/log/stats?"+query, nil), false},
+				{httptest.NewRequest("GET", "/log/stats?"+glob, nil), true},
+				{httptest.NewRequest("GET", "/log/search?"+glob, nil), true},
+				{httptest.NewRequest("POST", "/log/clearFileContent", strings.NewReader(query)), false},
+			}
+			for _, tt := range requests {
+				req := tt.req
+				if req.Method == "POST" {
+					req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				}
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+				body := w.Body.String()
+				if strings.Contains(body, "TOP SECRET") {
+					t.Fatalf("%s %s leaked the file: %s", req.Method, req.URL, body)
+				}
+
+				switch {
+				case tt.filtered:
+					if w.Code == http.StatusOK && strings.Contains(body, "secret.log") {
+						t.Errorf("%s %s: expected no files outside LogDir, got %s", req.Method, req.URL, body)
+					}
+				case req.URL.Path == "/log/exportFile" && req.URL.Query().Get("format") == "":
+					var response map[string]interface{}
+					json.Unmarshal(w.Body.Bytes(), &response)
+					if response["code"] == float64(200) {
+						t.Errorf("Expected export of %q to be rejected, got %v", name, response)
+					}
+				default:
+					if w.Code == http.StatusOK {
+						t.Errorf("%s %s: expected %q to be rejected, got 200", req.Method, req.URL, name)
+					}
+				}
+			}
+			checkSecret(t, secret)
+		})
+	}
+
+	// 越界访问返回 400，与文件不存在区分
+	req := httptest.NewRequest("GET", "/log/getFileContent?name=link.log", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status BadRequest for symlink outside LogDir, got %d", w.Code)
+	}
+}
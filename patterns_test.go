@@ -0,0 +1,109 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 18:20:05
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 18:20:05
+ * Description: 文件通配符规则测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// mixedFiles 包含日志、pid、锁文件和 core dump 的测试文件
+var mixedFiles = infoFiles("app.log", "app.log.gz", "app.pid", "app.lock", "core.1234", "tmp/cache.log")
+
+func TestPatterns_List(t *testing.T) {
+	tests := []struct {
+		include []string
+		exclude []string
+		want    string
+	}{
+		{nil, nil, "app.lock,app.log,app.log.gz,app.pid,core.1234,tmp/cache.log"},
+		{[]string{"*.log", "*.log.gz"}, nil, "app.log,app.log.gz,tmp/cache.log"},
+		{nil, []string{"*.pid", "*.lock", "core*"}, "app.log,app.log.gz,tmp/cache.log"},
+		{[]string{"*.log"}, []string{"tmp"}, "app.log"},
+		{nil, []string{"tmp/*.log", "*.gz"}, "app.lock,app.log,app.pid,core.1234"},
+	}
+	for _, tt := range tests {
+		lv := newTestViewer(t, mixedFiles, &Config{IncludePatterns: tt.include, ExcludePatterns: tt.exclude})
+		files, err := lv.ListLogFiles(ListOptions{Recursive: true})
+		if err != nil {
+			t.Fatalf("ListLogFiles failed: %v", err)
+		}
+		var names []string
+		for _, f := range files {
+			names = append(names, f.Name)
+		}
+		if got := strings.Join(names, ","); got != tt.want {
+			t.Errorf("include %v exclude %v: got %s, want %s", tt.include, tt.exclude, got, tt.want)
+		}
+	}
+
+	lv := newTestViewer(t, mixedFiles, &Config{ExcludePatterns: []string{"*.pid", "*.lock", "core*"}})
+	names, err := lv.GetLogFiles()
+	if err != nil || strings.Join(names, ",") != "app.log,app.log.gz" {
+		t.Errorf("Unexpected GetLogFiles result: %v %v", names, err)
+	}
+}
+
+func TestPatterns_Access(t *testing.T) {
+	lv := newTestViewer(t, mixedFiles, &Config{
+		DevMode:         true,
+		EnableDelete:    true,
+		EnableClear:     true,
+		EnableExport:    true,
+		IncludePatterns: []string{"*.log", "*.log.gz"},
+		ExcludePatterns: []string{"tmp"},
+	})
+
+	for _, name := range []string{"app.pid", "core.1234", "tmp/cache.log"} {
+		if _, err := lv.GetLogContent(name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("GetLogContent(%q) = %v, want fs.ErrNotExist", name, err)
+		}
+		if _, err := lv.GetLogPage(name, PageQuery{}); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("GetLogPage(%q) = %v, want fs.ErrNotExist", name, err)
+		}
+		if err := lv.Tail(context.Background(), name, nil, func(TailEvent) error { return nil }); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Tail(%q) = %v, want fs.ErrNotExist", name, err)
+		}
+		if err := lv.ClearFileContent(name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("ClearFileContent(%q) = %v, want fs.ErrNotExist", name, err)
+		}
+
+		req := httptest.NewRequest("GET", "/log/exportFile?name="+name, nil)
+		w := httptest.NewRecorder()
+		lv.ExportFileHandler(w, req)
+		var response struct {
+			Code int `json:"code"`
+		}
+		json.NewDecoder(w.Body).Decode(&response)
+		if response.Code != 3004 {
+			t.Errorf("Expected export of %q to report file not found, got %d", name, response.Code)
+		}
+	}
+
+	if err := lv.DeleteAllLogs(); err != nil {
+		t.Fatalf("DeleteAllLogs failed: %v", err)
+	}
+	for _, name := range []string{"app.pid", "app.lock", "core.1234", "tmp/cache.log"} {
+		if _, err := os.Stat(filepath.Join(lv.config.LogDir, filepath.FromSlash(name))); err != nil {
+			t.Errorf("Expected hidden file %s to be kept: %v", name, err)
+		}
+	}
+	for _, name := range []string{"app.log", "app.log.gz"} {
+		if _, err := os.Stat(filepath.Join(lv.config.LogDir, name)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be deleted", name)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 21:58:40
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 21:58:40
+ * Description: 基于角色的操作权限
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 操作权限
+const (
+	PermView   = "view"   // 查看文件列表及内容
+	PermTail   = "tail"   // 实时跟踪
+	PermExport = "export" // 导出
+	PermClear  = "clear"  // 清空文件
+	PermDelete = "delete" // 删除文件
+	PermAudit  = "audit"  // 查看审计日志
+	PermAll    = "*"      // 全部权限
+
+	permIndex = "index" // 后台建立持久索引时读取文件，不检查用户权限
+)
+
+var (
+	// ErrForbidden 当前用户没有操作权限
+	ErrForbidden = errors.New("permission denied")
+	// ErrDisabled 未配置角色时，操作被 DevMode 或 Enable* 开关禁用
+	ErrDisabled = errors.New("operation is disabled")
+)
+
+// Role 角色，Sources 和 Files 为空时不限制范围
+type Role struct {
+	Name        string
+	Permissions []string // 允许的操作，如 PermView、PermExport，PermAll 表示全部
+	Sources     []string // 限定的日志源名称
+	Files       []string // 限定的文件通配符，规则与 FileFormats 相同
+}
+
+// allows 判断角色是否允许在日志源中对文件执行操作，name 为空表示日志源中的任意文件
+func (role *Role) allows(perm, source, name string) bool {
+	if !contains(role.Permissions, perm) && !contains(role.Permissions, PermAll) {
+		return false
+	}
+	if len(role.Sources) > 0 && !contains(role.Sources, source) {
+		return false
+	}
+	return name == "" || included(role.Files, name)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// roles 返回用户的全部角色：Identity.Roles 及 Config.UserRoles 中为该用户配置的角色
+func (lv *LogViewer) roles(identity *Identity) []*Role {
+	if identity == nil {
+		return nil
+	}
+	names := append(append([]string{}, identity.Roles...), lv.config.UserRoles[identity.Name]...)
+	var roles []*Role
+	for i := range lv.config.Roles {
+		if contains(names, lv.config.Roles[i].Name) {
+			roles = append(roles, &lv.config.Roles[i])
+		}
+	}
+	return roles
+}
+
+// As 返回以指定用户身份操作的日志源，配置了 Roles 时各方法按该用户的角色检查权限
+func (s *Source) As(identity *Identity) *Source {
+	clone := *s
+	clone.identity = identity
+	return &clone
+}
+
+// Identity 返回日志源绑定的用户
+func (s *Source) Identity() *Identity {
+	return s.identity
+}
+
+// authorize 检查当前用户能否对文件执行操作，name 为空时检查能否对日志源中的任意文件执行操作。
+// 未配置 Roles 时沿用 DevMode 及 Enable* 开关：查看和跟踪总是允许
+func (s *Source) authorize(perm, name string) error {
+	if perm == permIndex {
+		return nil
+	}
+	if len(s.lv.config.Roles) == 0 {
+		switch perm {
+		case PermExport:
+			if !s.config.EnableExport {
+				return fmt.Errorf("%s %w", perm, ErrDisabled)
+			}
+		case PermClear, PermDelete:
+			enabled := s.config.EnableClear
+			if perm == PermDelete {
+				enabled = s.config.EnableDelete
+			}
+			if !s.lv.config.DevMode || !enabled {
+				return fmt.Errorf("%s %w", perm, ErrDisabled)
+			}
+		}
+		return nil
+	}
+
+	for _, role := range s.lv.roles(s.identity) {
+		if role.allows(perm, s.config.Name, name) {
+			return nil
+		}
+	}
+	user := "anonymous"
+	if s.identity != nil {
+		user = s.identity.Name
+	}
+	if name == "" {
+		return fmt.Errorf("%w: %s cannot %s in source %q", ErrForbidden, user, perm, s.config.Name)
+	}
+	return fmt.Errorf("%w: %s cannot %s %q", ErrForbidden, user, perm, name)
+}
+
+// Can 判断当前用户能否对文件执行操作，name 为空时判断能否对日志源中的任意文件执行操作
+func (s *Source) Can(perm, name string) bool {
+	return s.authorize(perm, name) == nil
+}
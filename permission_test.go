@@ -0,0 +1,156 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 22:24:51
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 22:24:51
+ * Description: 角色权限测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newRolesViewer 创建配置了角色的 LogViewer：oncall 可导出，sre 可清空，没有角色可以删除，
+// auditor 只能查看 audit-*.log，worker-dev 可在 worker 日志源中做任何操作
+func newRolesViewer(t *testing.T) (*LogViewer, string) {
+	t.Helper()
+	apiDir, workerDir := t.TempDir(), t.TempDir()
+	for _, name := range []string{"app.log", "audit-2026.log"} {
+		appendLine(t, filepath.Join(apiDir, name), `{"level":"INFO","msg":"`+name+`"}`+"\n")
+	}
+	appendLine(t, filepath.Join(workerDir, "worker.log"), `{"level":"INFO","msg":"worker"}`+"\n")
+
+	lv := New(&Config{
+		DevMode:      true,
+		BearerTokens: map[string]string{"oncall-token": "olga", "sre-token": "sam"},
+		Sources: []SourceConfig{
+			{Name: "api", Dir: apiDir, EnableDelete: true, EnableClear: true, EnableExport: true},
+			{Name: "worker", Dir: workerDir},
+		},
+		Roles: []Role{
+			{Name: "oncall", Permissions: []string{PermView, PermTail, PermExport}},
+			{Name: "sre", Permissions: []string{PermView, PermTail, PermExport, PermClear}},
+			{Name: "auditor", Permissions: []string{PermView}, Files: []string{"audit-*.log"}},
+			{Name: "worker-dev", Permissions: []string{PermAll}, Sources: []string{"worker"}},
+		},
+		UserRoles: map[string][]string{"olga": {"oncall"}, "sam": {"sre"}, "ada": {"auditor"}, "wes": {"worker-dev"}},
+	})
+	return lv, apiDir
+}
+
+// sourceAs 以指定用户获取日志源
+func sourceAs(t *testing.T, lv *LogViewer, source string, identity *Identity) *Source {
+	t.Helper()
+	s, err := lv.Source(source)
+	if err != nil {
+		t.Fatalf("Source(%q) failed: %v", source, err)
+	}
+	return s.As(identity)
+}
+
+func TestRoles_Core(t *testing.T) {
+	lv, apiDir := newRolesViewer(t)
+
+	oncall := sourceAs(t, lv, "api", &Identity{Name: "olga"})
+	if _, err := oncall.ExportContent("app.log"); err != nil {
+		t.Errorf("oncall should export: %v", err)
+	}
+	if err := oncall.ClearFileContent("app.log"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("oncall clear = %v, want ErrForbidden", err)
+	}
+
+	sre := sourceAs(t, lv, "api", &Identity{Name: "sam"})
+	if err := sre.ClearFileContent("app.log"); err != nil {
+		t.Errorf("sre should clear: %v", err)
+	}
+	// 即使日志源开启了 EnableDelete，配置角色后也以角色为准
+	if err := sre.DeleteAllLogs(); !errors.Is(err, ErrForbidden) {
+		t.Errorf("sre delete = %v, want ErrForbidden", err)
+	}
+	if _, err := os.Stat(filepath.Join(apiDir, "app.log")); err != nil {
+		t.Errorf("File should not be deleted: %v", err)
+	}
+
+	// 未认证的用户没有任何权限
+	anonymous := sourceAs(t, lv, "api", nil)
+	if _, err := anonymous.GetLogContent("app.log"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("anonymous view = %v, want ErrForbidden", err)
+	}
+	if err := lv.ClearFileContent("app.log"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("LogViewer.ClearFileContent without identity = %v, want ErrForbidden", err)
+	}
+
+	// 文件范围
+	auditor := sourceAs(t, lv, "api", &Identity{Name: "ada"})
+	files, err := auditor.ListLogFiles(ListOptions{})
+	if err != nil || len(files) != 1 || files[0].Name != "audit-2026.log" {
+		t.Errorf("Unexpected files for auditor: %v %v", files, err)
+	}
+	if _, err := auditor.GetLogPage("app.log", PageQuery{}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("auditor view app.log = %v, want ErrForbidden", err)
+	}
+
+	// 日志源范围，角色也可以由认证器直接给出
+	dev := &Identity{Name: "someone", Roles: []string{"worker-dev"}}
+	if _, err := sourceAs(t, lv, "api", dev).GetLogFiles(); !errors.Is(err, ErrForbidden) {
+		t.Errorf("worker-dev list api = %v, want ErrForbidden", err)
+	}
+	if err := sourceAs(t, lv, "worker", dev).DeleteAllLogs(); err != nil {
+		t.Errorf("worker-dev should delete in worker: %v", err)
+	}
+}
+
+func TestRoles_Handlers(t *testing.T) {
+	lv, _ := newRolesViewer(t)
+	handler := lv.Handler("/log")
+
+	do := func(method, path, token string, form url.Values) *httptest.ResponseRecorder {
+		var req *http.Request
+		if form != nil {
+			req = httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		} else {
+			req = httptest.NewRequest(method, path, nil)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("POST", "/log/clearFileContent", "oncall-token", url.Values{"name": {"app.log"}}); w.Code != http.StatusForbidden {
+		t.Errorf("Expected oncall clear to be forbidden, got %d", w.Code)
+	}
+	if w := do("POST", "/log/clearFileContent", "sre-token", url.Values{"name": {"app.log"}}); w.Code != http.StatusOK {
+		t.Errorf("Expected sre clear to succeed, got %d", w.Code)
+	}
+	if w := do("POST", "/log/deleteAllFiles", "sre-token", url.Values{}); w.Code != http.StatusForbidden {
+		t.Errorf("Expected delete to be forbidden, got %d", w.Code)
+	}
+	if w := do("GET", "/log/exportFile?name=app.log", "oncall-token", nil); !strings.Contains(w.Body.String(), `"code":200`) {
+		t.Errorf("Expected oncall export to succeed, got %s", w.Body.String())
+	}
+
+	w := do("GET", "/log/getSources", "oncall-token", nil)
+	var response struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	json.NewDecoder(w.Body).Decode(&response)
+	if len(response.Data) != 2 {
+		t.Fatalf("Unexpected sources: %v", response.Data)
+	}
+	api := response.Data[0]
+	if api["enableExport"] != true || api["enableTail"] != true || api["enableClear"] != false || api["enableDelete"] != false {
+		t.Errorf("Unexpected permissions: %v", api)
+	}
+}
@@ -0,0 +1,501 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/18 03:52:19
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/18 03:52:19
+ * Description: 日志查询表达式，如 level>=WARN AND user_id=42 AND msg~"timeout" AND duration_ms>500
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 查询中的内置字段，其余字段名均为属性，分组属性用 "." 连接
+const (
+	queryFieldLevel = "level"
+	queryFieldTime  = "time"
+	queryFieldMsg   = "msg"
+)
+
+// QueryError 查询表达式的解析错误
+type QueryError struct {
+	Query string // 查询表达式
+	Pos   int    // 出错位置，从 1 开始的字符序号，表达式意外结束时为长度加 1
+	Msg   string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("invalid query at position %d: %s", e.Pos, e.Msg)
+}
+
+// Query 解析后的查询表达式，语法：
+//
+//	条件      字段 操作符 值，或 字段 exists
+//	操作符    = != > >= < <= ~（正则匹配）!~（正则不匹配）
+//	组合      AND、OR、NOT 及括号，优先级 NOT > AND > OR，关键字不区分大小写
+//	值        不含空白、括号及操作符的单词，或双引号字符串（\" 表示引号，\\ 表示反斜杠，其余原样保留）
+//
+// 字段 level、time、msg 对应日志的级别、时间和消息，其余为属性。level 按级别比较，time 按时间比较；
+// 属性按值的类型比较：值为数字时按数字，为时长（如 500ms）时按时长，数字属性视为纳秒（slog 记录 time.Duration 的方式），
+// 为时间或 true/false 时按时间或布尔值，无法按类型比较时按字符串比较。
+// != 和 !~ 分别为 = 和 ~ 的否定，属性不存在时成立
+type Query struct {
+	text string
+	root queryNode
+}
+
+// ParseQuery 解析查询表达式，失败时返回 *QueryError
+func ParseQuery(s string) (*Query, error) {
+	p := &queryParser{text: s}
+	if err := p.lex(); err != nil {
+		return nil, err
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorf(tok, "expected AND, OR or end of query, got %s", tok)
+	}
+	return &Query{text: s, root: root}, nil
+}
+
+// String 返回查询表达式
+func (q *Query) String() string {
+	return q.text
+}
+
+// Match 判断日志是否满足查询条件
+func (q *Query) Match(entry *LogEntry) bool {
+	return q == nil || q.root.match(entry)
+}
+
+type queryNode interface {
+	match(entry *LogEntry) bool
+}
+
+type andNode struct{ left, right queryNode }
+
+func (n andNode) match(entry *LogEntry) bool { return n.left.match(entry) && n.right.match(entry) }
+
+type orNode struct{ left, right queryNode }
+
+func (n orNode) match(entry *LogEntry) bool { return n.left.match(entry) || n.right.match(entry) }
+
+type notNode struct{ node queryNode }
+
+func (n notNode) match(entry *LogEntry) bool { return !n.node.match(entry) }
+
+type existsNode struct{ field string }
+
+func (n existsNode) match(entry *LogEntry) bool {
+	_, ok := queryField(entry, n.field)
+	return ok
+}
+
+// compareNode 字段与值的比较
+type compareNode struct {
+	field string
+	op    string
+	value queryValue
+	re    *regexp.Regexp // ~ 和 !~ 的正则表达式
+}
+
+func (n compareNode) match(entry *LogEntry) bool {
+	switch n.op {
+	case "!=":
+		return !compareNode{field: n.field, op: "=", value: n.value}.match(entry)
+	case "!~":
+		return !compareNode{field: n.field, op: "~", re: n.re}.match(entry)
+	}
+
+	v, ok := queryField(entry, n.field)
+	if !ok {
+		return false
+	}
+	if n.op == "~" {
+		return n.re.MatchString(cellText(v))
+	}
+
+	var c int
+	switch n.field {
+	case queryFieldLevel:
+		level, err := ParseLevel(entry.Level)
+		if err != nil {
+			return false
+		}
+		c = cmp.Compare(level, n.value.level)
+	case queryFieldTime:
+		t, err := ParseTime(entry.Time)
+		if err != nil {
+			return false
+		}
+		c = t.Compare(n.value.time)
+	default:
+		if c, ok = n.value.compare(v); !ok {
+			return false
+		}
+	}
+
+	switch n.op {
+	case "=":
+		return c == 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	}
+	return false
+}
+
+// queryField 返回字段的值，内置字段为空或属性不存在时返回 false
+func queryField(entry *LogEntry, field string) (interface{}, bool) {
+	switch field {
+	case queryFieldLevel:
+		return entry.Level, entry.Level != ""
+	case queryFieldTime:
+		return entry.Time, entry.Time != ""
+	case queryFieldMsg:
+		return entry.Msg, entry.Msg != ""
+	}
+	return lookupAttr(entry.Attrs, field)
+}
+
+// queryValue 比较值，解析时确定其可以表示的类型
+type queryValue struct {
+	text string
+
+	isInt    bool
+	int      int64
+	isNumber bool
+	number   float64
+	isDur    bool
+	dur      time.Duration
+	isTime   bool
+	time     time.Time
+	isBool   bool
+	bool     bool
+	level    slog.Level
+}
+
+func newQueryValue(text string) queryValue {
+	v := queryValue{text: text}
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		v.isInt, v.int = true, n
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		v.isNumber, v.number = true, f
+	} else if d, err := time.ParseDuration(text); err == nil {
+		v.isDur, v.dur = true, d
+	} else if t, err := ParseTime(text); err == nil {
+		v.isTime, v.time = true, t
+	} else if b := strings.ToLower(text); b == "true" || b == "false" {
+		v.isBool, v.bool = true, b == "true"
+	}
+	return v
+}
+
+// compare 按属性值的类型与比较值比较，类型不能比较时返回 false
+func (v queryValue) compare(attr interface{}) (int, bool) {
+	switch a := attr.(type) {
+	case json.Number:
+		if v.isInt {
+			if n, err := a.Int64(); err == nil {
+				return cmp.Compare(n, v.int), true
+			}
+		}
+		if f, err := a.Float64(); err == nil {
+			return v.compareNumber(f)
+		}
+	case float64:
+		return v.compareNumber(a)
+	case bool:
+		if v.isBool {
+			return cmp.Compare(boolInt(a), boolInt(v.bool)), true
+		}
+	case string:
+		switch {
+		case v.isInt:
+			if n, err := strconv.ParseInt(a, 10, 64); err == nil {
+				return cmp.Compare(n, v.int), true
+			}
+			fallthrough
+		case v.isNumber:
+			if f, err := strconv.ParseFloat(a, 64); err == nil {
+				return cmp.Compare(f, v.number), true
+			}
+		case v.isDur:
+			if d, err := time.ParseDuration(a); err == nil {
+				return cmp.Compare(d, v.dur), true
+			}
+		case v.isTime:
+			if t, err := ParseTime(a); err == nil {
+				return t.Compare(v.time), true
+			}
+		case v.isBool:
+			if b, err := strconv.ParseBool(a); err == nil {
+				return cmp.Compare(boolInt(b), boolInt(v.bool)), true
+			}
+		}
+		return strings.Compare(a, v.text), true
+	case nil:
+		return 0, v.text == "null"
+	}
+	return strings.Compare(cellText(attr), v.text), true
+}
+
+// compareNumber 与数字属性比较，比较值为时长时属性视为纳秒
+func (v queryValue) compareNumber(f float64) (int, bool) {
+	switch {
+	case v.isNumber:
+		return cmp.Compare(f, v.number), true
+	case v.isDur:
+		return cmp.Compare(f, float64(v.dur)), true
+	}
+	return 0, false
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// 词法单元类型
+const (
+	tokenEOF = iota
+	tokenWord
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+)
+
+type queryToken struct {
+	kind int
+	text string
+	pos  int // 从 1 开始的字符序号
+}
+
+func (t queryToken) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// is 判断是否为指定的关键字，不区分大小写
+func (t queryToken) is(keyword string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+type queryParser struct {
+	text   string
+	tokens []queryToken
+	next   int
+}
+
+func (p *queryParser) errorf(tok queryToken, format string, args ...interface{}) error {
+	return &QueryError{Query: p.text, Pos: tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// isOpChar 判断是否为操作符字符
+func isOpChar(r rune) bool {
+	return strings.ContainsRune("=!<>~", r)
+}
+
+// lex 将表达式切分为词法单元
+func (p *queryParser) lex() error {
+	runes := []rune(p.text)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			i++
+			continue
+		case r == '(':
+			p.tokens = append(p.tokens, queryToken{kind: tokenLParen, text: "(", pos: start + 1})
+			i++
+		case r == ')':
+			p.tokens = append(p.tokens, queryToken{kind: tokenRParen, text: ")", pos: start + 1})
+			i++
+		case r == '"':
+			var sb strings.Builder
+			for i++; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\') {
+					i++
+				}
+				sb.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return &QueryError{Query: p.text, Pos: start + 1, Msg: "unterminated string"}
+			}
+			i++
+			p.tokens = append(p.tokens, queryToken{kind: tokenString, text: sb.String(), pos: start + 1})
+		case isOpChar(r):
+			op := string(r)
+			if i+1 < len(runes) && runes[i+1] == '=' && r != '=' && r != '~' {
+				op += "="
+			} else if r == '!' && i+1 < len(runes) && runes[i+1] == '~' {
+				op = "!~"
+			} else if r == '=' && i+1 < len(runes) && runes[i+1] == '=' {
+				op = "=="
+			}
+			if op == "!" {
+				return &QueryError{Query: p.text, Pos: start + 1, Msg: `unexpected "!", use NOT or != / !~`}
+			}
+			i += len(op)
+			if op == "==" {
+				op = "="
+			}
+			p.tokens = append(p.tokens, queryToken{kind: tokenOp, text: op, pos: start + 1})
+		default:
+			for i < len(runes) && !strings.ContainsRune(" \t\n\r()\"", runes[i]) && !isOpChar(runes[i]) {
+				i++
+			}
+			p.tokens = append(p.tokens, queryToken{kind: tokenWord, text: string(runes[start:i]), pos: start + 1})
+		}
+	}
+	p.tokens = append(p.tokens, queryToken{kind: tokenEOF, pos: len(runes) + 1})
+	return nil
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.next]
+}
+
+func (p *queryParser) advance() queryToken {
+	tok := p.tokens[p.next]
+	if tok.kind != tokenEOF {
+		p.next++
+	}
+	return tok
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("OR") {
+		p.advance()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("AND") {
+		p.advance()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseNot() (queryNode, error) {
+	if p.peek().is("NOT") {
+		p.advance()
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	tok := p.advance()
+	switch {
+	case tok.kind == tokenLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if next := p.peek(); next.kind != tokenRParen {
+			if next.kind == tokenEOF {
+				return nil, p.errorf(tok, "unclosed parenthesis")
+			}
+			return nil, p.errorf(next, "expected ), got %s", next)
+		}
+		p.advance()
+		return node, nil
+	case tok.kind == tokenEOF:
+		return nil, p.errorf(tok, "unexpected end of query, expected a condition")
+	case tok.kind == tokenWord && (tok.is("AND") || tok.is("OR")), tok.kind == tokenRParen, tok.kind == tokenOp:
+		return nil, p.errorf(tok, "unexpected %s, expected a condition", tok)
+	}
+
+	field := tok.text
+	next := p.advance()
+	if next.is("exists") {
+		return existsNode{field: field}, nil
+	}
+	if next.kind != tokenOp {
+		return nil, p.errorf(next, "expected operator or exists after %s, got %s", tok, next)
+	}
+	value := p.advance()
+	if value.kind != tokenWord && value.kind != tokenString {
+		return nil, p.errorf(value, "expected value after %q, got %s", next.text, value)
+	}
+	return p.compare(field, next.text, value)
+}
+
+// compare 创建比较条件，检查正则表达式以及 level、time 字段的值
+func (p *queryParser) compare(field, op string, value queryToken) (queryNode, error) {
+	node := compareNode{field: field, op: op, value: newQueryValue(value.text)}
+	if op == "~" || op == "!~" {
+		re, err := regexp.Compile(value.text)
+		if err != nil {
+			return nil, p.errorf(value, "invalid regex: %v", err)
+		}
+		node.re = re
+		return node, nil
+	}
+
+	switch field {
+	case queryFieldLevel:
+		level, err := ParseLevel(value.text)
+		if err != nil {
+			return nil, p.errorf(value, "invalid level %q", value.text)
+		}
+		node.value.level = level
+	case queryFieldTime:
+		t, err := ParseTime(value.text)
+		if err != nil {
+			return nil, p.errorf(value, "invalid time %q", value.text)
+		}
+		node.value.time = t
+	}
+	return node, nil
+}
@@ -0,0 +1,153 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/18 04:31:06
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/18 04:31:06
+ * Description: 查询表达式测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestQuery_Match(t *testing.T) {
+	lines := []string{
+		`{"time":"2026-10-01T14:00:00Z","level":"INFO","msg":"request done","user_id":42,"duration_ms":120,"cached":true}`,
+		`{"time":"2026-10-01T14:05:00Z","level":"WARN","msg":"upstream timeout","user_id":42,"duration_ms":1500,"latency":2000000000}`,
+		`{"time":"2026-10-01T14:10:00Z","level":"ERROR","msg":"db timeout","user_id":"7","http":{"status":503,"path":"/api/v1"}}`,
+		`{"time":"2026-10-01T14:20:00Z","level":"ERROR","msg":"panic","user_id":9007199254740993,"deadline":"2026-10-01T15:00:00Z"}`,
+		`time=2026-10-01T14:30:00Z level=DEBUG msg="text line" elapsed=1.5s ok=false`,
+	}
+	var entries []LogEntry
+	for i, line := range lines {
+		parser := LineParser(JSONParser{})
+		if i == len(lines)-1 {
+			parser = TextParser{}
+		}
+		entry, ok := parser.Parse([]byte(line))
+		if !ok {
+			t.Fatalf("Failed to parse %s", line)
+		}
+		entries = append(entries, entry)
+	}
+
+	tests := []struct {
+		query string
+		want  string // 匹配的日志序号
+	}{
+		{`level>=WARN AND user_id=42 AND msg~"timeout" AND duration_ms>500`, "1"},
+		{`level>=warn`, "123"},
+		{`level<INFO`, "4"},
+		{`level=ERROR`, "23"},
+		{`user_id=42`, "01"},
+		{`user_id=7`, "2"},
+		{`user_id=9007199254740993`, "3"},
+		{`user_id!=42`, "234"},
+		{`duration_ms>=120 AND duration_ms<1500`, "0"},
+		{`latency>1.5s`, "1"},
+		{`elapsed>1s AND elapsed<=1500ms`, "4"},
+		{`cached=true`, "0"},
+		{`ok=FALSE`, "4"},
+		{`http.status>=500`, "2"},
+		{`http.path="/api/v1"`, "2"},
+		{`http exists`, "2"},
+		{`NOT user_id exists`, "4"},
+		{`deadline>2026-10-01T14:59:00Z`, "3"},
+		{`time>="2026-10-01 14:10:00" AND time<2026-10-01T14:30:00Z`, "23"},
+		{`msg~"^(db|upstream) " OR msg="panic"`, "123"},
+		{`msg!~timeout AND level>=INFO`, "03"},
+		{`NOT (level=ERROR OR level=WARN) AND msg~line`, "4"},
+		{`level=ERROR AND (user_id=7 OR user_id=42)`, "2"},
+		{`level=INFO OR level=WARN AND user_id=7`, "0"},
+		{`not level=error and not level=warn`, "04"},
+		{`msg="upstream \"x\"" OR msg == "db timeout"`, "2"},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Errorf("%s: %v", tt.query, err)
+			continue
+		}
+		var got strings.Builder
+		for i := range entries {
+			if q.Match(&entries[i]) {
+				got.WriteByte(byte('0' + i))
+			}
+		}
+		if got.String() != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.query, tt.want, got.String())
+		}
+	}
+}
+
+func TestParseQuery_Errors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+		msg   string
+	}{
+		{``, 1, "unexpected end of query"},
+		{`level>=`, 8, `expected value after ">="`},
+		{`level>=WARN AND`, 16, "unexpected end of query"},
+		{`level>=WARN user_id=1`, 13, "expected AND, OR or end of query"},
+		{`user_id 42`, 9, "expected operator or exists"},
+		{`(level=INFO OR level=WARN`, 1, "unclosed parenthesis"},
+		{`level=INFO)`, 11, "expected AND, OR or end of query"},
+		{`msg~"(unclosed"`, 5, "invalid regex"},
+		{`msg="open`, 5, "unterminated string"},
+		{`level>=LOUD`, 8, "invalid level"},
+		{`time>yesterday`, 6, "invalid time"},
+		{`a=1 AND !b=2`, 9, `unexpected "!"`},
+		{`AND a=1`, 1, "unexpected"},
+		{`错误="x" AND ?`, 13, "expected operator or exists"},
+	}
+	for _, tt := range tests {
+		_, err := ParseQuery(tt.query)
+		var qerr *QueryError
+		if !errors.As(err, &qerr) {
+			t.Errorf("%q: expected QueryError, got %v", tt.query, err)
+			continue
+		}
+		if qerr.Pos != tt.pos || !strings.Contains(qerr.Msg, tt.msg) {
+			t.Errorf("%q: expected %q at %d, got %v", tt.query, tt.msg, tt.pos, err)
+		}
+	}
+}
+
+func TestQuery_Handlers(t *testing.T) {
+	lv := newTestViewer(t, exportFiles, &Config{EnableExport: true})
+	handler := lv.Handler("/log")
+	q := url.QueryEscape(`level=ERROR AND request_id=r1 AND (http.status>=500 OR retry=true)`)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/log/getFileContent?name=app.log&q="+q, nil))
+	var page LogPage
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil || page.Total != 2 {
+		t.Errorf("Unexpected content: %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/log/exportFile?format=ndjson&name=app.log&q="+q, nil))
+	if w.Code != http.StatusOK || strings.Count(w.Body.String(), "\n") != 2 {
+		t.Errorf("Unexpected export: %d %s", w.Code, w.Body.String())
+	}
+
+	for _, path := range []string{"/log/getFileContent", "/log/exportFile?format=csv&", "/log/tail"} {
+		if !strings.Contains(path, "?") {
+			path += "?"
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", path+"name=app.log&q="+url.QueryEscape("level>="), nil))
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "position 8") {
+			t.Errorf("%s: expected 400 with error position, got %d %s", path, w.Code, w.Body.String())
+		}
+	}
+}
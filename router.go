@@ -0,0 +1,132 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 19:03:48
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 19:03:48
+ * Description: net/http 路由
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/zjguoxin/goslogviewer/internal/ipfilter"
+)
+
+const defaultBasePath = "/log"
+
+// BasePath 返回适配器注册的路径前缀，未配置时为 /log，根路径返回空字符串
+func (lv *LogViewer) BasePath() string {
+	base := lv.config.BasePath
+	if base == "" {
+		base = defaultBasePath
+	}
+	return strings.TrimSuffix("/"+strings.Trim(base, "/"), "/")
+}
+
+// Handler 返回挂载在 prefix（如 "/log"）下的完整处理器，包含页面、内嵌静态资源、全部接口、认证及IP限制，
+// 页面和静态资源无需认证，登录由页面完成：
+//
+//	mux.Handle("/log/", lv.Handler("/log"))
+//	mux.Handle("/log", lv.Handler("/log"))
+func (lv *LogViewer) Handler(prefix string) http.Handler {
+	prefix = strings.TrimSuffix(prefix, "/")
+	mux := http.NewServeMux()
+
+	page := lv.PageHandler(prefix)
+	if prefix == "" {
+		mux.Handle("GET /{$}", page)
+	} else {
+		mux.Handle("GET "+prefix, page)
+		mux.Handle("GET "+prefix+"/{$}", page)
+	}
+	mux.Handle("GET "+prefix+"/static/", http.StripPrefix(prefix+"/static/", http.FileServerFS(Assets())))
+
+	routes := []struct {
+		method  string
+		path    string
+		handler http.HandlerFunc
+	}{
+		{http.MethodGet, "/getLogFilesList", lv.GetFilesHandler},
+		{http.MethodGet, "/getFileContent", lv.GetContentHandler},
+		{http.MethodPost, "/clearFileContent", lv.ClearFileContentHandler},
+		{http.MethodPost, "/deleteAllFiles", lv.DeleteAllFilesHandler},
+		{http.MethodGet, "/exportFile", lv.ExportFileHandler},
+		{http.MethodGet, "/exportBundle", lv.ExportBundleHandler},
+		{http.MethodGet, "/search", lv.SearchHandler},
+		{http.MethodGet, "/stats", lv.StatsHandler},
+		{http.MethodGet, "/tail", lv.TailHandler},
+		{http.MethodGet, "/getSources", lv.GetSourcesHandler},
+		{http.MethodGet, "/audit", lv.AuditHandler},
+	}
+	for _, route := range routes {
+		mux.Handle(route.method+" "+prefix+route.path, lv.requireAuth(route.handler))
+	}
+
+	return lv.ipRestriction(mux)
+}
+
+// PageHandler 返回日志查看页面，base 为页面中静态资源及接口地址的前缀
+func (lv *LogViewer) PageHandler(base string) http.HandlerFunc {
+	base = strings.TrimSuffix(base, "/")
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err := pageTemplate.ExecuteTemplate(w, "log.html", map[string]interface{}{
+			"head":     "日志查看器",
+			"title":    "日志查看器",
+			"pageSize": lv.config.PageSize,
+			"base":     base,
+			"auth":     lv.AuthEnabled(),
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+// ipRestriction 按 EnableIPRestriction 限制访问，只有直连地址为可信代理时才读取 X-Forwarded-For
+func (lv *LogViewer) ipRestriction(next http.Handler) http.Handler {
+	if !lv.config.EnableIPRestriction {
+		return next
+	}
+	filter := ipfilter.New(lv.config.AllowedIPs, lv.config.TrustedProxies)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientIP := clientIP(filter, r)
+		if filter.Allowed(clientIP) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		lv.record(AuditEvent{
+			IP:      clientIP,
+			Action:  ActionAccess,
+			Path:    r.URL.Path,
+			Outcome: AuditDenied,
+			Error:   "ip not allowed",
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		respondJSON(w, map[string]interface{}{
+			"code":    403,
+			"message": "Access denied for IP: " + clientIP,
+			"your_ip": clientIP,
+		})
+	})
+}
+
+// clientIP 获取请求的客户端IP，规则与IP限制相同
+func (lv *LogViewer) clientIP(r *http.Request) string {
+	return clientIP(ipfilter.New(lv.config.AllowedIPs, lv.config.TrustedProxies), r)
+}
+
+// clientIP 只有直连地址为可信代理时才读取 X-Forwarded-For
+func clientIP(filter *ipfilter.Filter, r *http.Request) string {
+	forwardedFor := ""
+	if filter.TrustedProxy(r.RemoteAddr) {
+		forwardedFor = r.Header.Get("X-Forwarded-For")
+	}
+	return filter.ClientIP(r.RemoteAddr, forwardedFor)
+}
@@ -0,0 +1,142 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 19:20:14
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 19:20:14
+ * Description: net/http 路由测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHandler_Routes(t *testing.T) {
+	tempDir := t.TempDir()
+	appendLine(t, filepath.Join(tempDir, "app.log"), `{"level":"INFO","msg":"hello"}`+"\n")
+	lv := New(&Config{LogDir: tempDir, PageSize: 20})
+
+	// 挂载到调用方自己的 ServeMux
+	mux := http.NewServeMux()
+	mux.Handle("/admin/logs/", lv.Handler("/admin/logs"))
+	mux.Handle("/admin/logs", lv.Handler("/admin/logs"))
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		want   string
+	}{
+		{"GET", "/admin/logs", http.StatusOK, `const base = "/admin/logs"`},
+		{"GET", "/admin/logs/", http.StatusOK, `href="/admin/logs/static/bootstrap.min.css"`},
+		{"GET", "/admin/logs/static/dashboard.css", http.StatusOK, ""},
+		{"GET", "/admin/logs/static/missing.css", http.StatusNotFound, ""},
+		{"GET", "/admin/logs/getLogFilesList", http.StatusOK, `"app.log"`},
+		{"GET", "/admin/logs/getFileContent?name=app.log", http.StatusOK, `"hello"`},
+		{"GET", "/admin/logs/getSources", http.StatusOK, `"default"`},
+		{"POST", "/admin/logs/getFileContent?name=app.log", http.StatusMethodNotAllowed, ""},
+		{"GET", "/admin/logs/unknown", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, ts.URL+tt.path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", tt.method, tt.path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.path, tt.status, resp.StatusCode)
+		}
+		if !strings.Contains(string(body), tt.want) {
+			t.Errorf("%s %s: expected body to contain %s", tt.method, tt.path, tt.want)
+		}
+	}
+}
+
+func TestHandler_IPRestriction(t *testing.T) {
+	lv := New(&Config{
+		LogDir:              t.TempDir(),
+		EnableIPRestriction: true,
+		AllowedIPs:          []string{"192.168.1.0/24"},
+		TrustedProxies:      []string{"10.0.0.1"},
+	})
+	handler := lv.Handler("/log")
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		status     int
+	}{
+		{"Allowed client", "192.168.1.10:1234", "", http.StatusOK},
+		{"Denied client", "172.16.0.5:1234", "", http.StatusForbidden},
+		{"Via trusted proxy", "10.0.0.1:1234", "192.168.1.20", http.StatusOK},
+		{"Denied via trusted proxy", "10.0.0.1:1234", "172.16.0.5", http.StatusForbidden},
+		{"Spoofed header", "172.16.0.5:1234", "192.168.1.20", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/log/getSources", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d", tt.status, w.Code)
+			}
+			if tt.status == http.StatusForbidden {
+				var response map[string]interface{}
+				json.NewDecoder(w.Body).Decode(&response)
+				if response["code"] != float64(403) || response["your_ip"] == "" {
+					t.Errorf("Unexpected response: %v", response)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_IPRestriction_LoopbackProxy(t *testing.T) {
+	lv := New(&Config{
+		LogDir:              t.TempDir(),
+		EnableIPRestriction: true,
+		AllowedIPs:          []string{"127.0.0.1"},
+		TrustedProxies:      []string{"::1", "127.0.0.1"},
+	})
+	handler := lv.Handler("/log")
+
+	// 位于本机的可信代理转发的请求按 X-Forwarded-For 判断，不能因代理地址为本机而放行
+	tests := []struct {
+		remoteAddr string
+		forwarded  string
+		status     int
+	}{
+		{"[::1]:1234", "8.8.8.8", http.StatusForbidden},
+		{"127.0.0.1:1234", "8.8.8.8", http.StatusForbidden},
+		{"[::1]:1234", "", http.StatusOK},
+		{"[::1]:1234", "::1", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/log/getSources", nil)
+		req.RemoteAddr = tt.remoteAddr
+		if tt.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("%s via %s: expected status %d, got %d", tt.forwarded, tt.remoteAddr, tt.status, w.Code)
+		}
+	}
+}
@@ -0,0 +1,403 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/18 05:02:37
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/18 05:02:37
+ * Description: 跨文件搜索，多个文件并行读取，结果按时间顺序合并后流式返回
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSearchLimit  = 100
+	maxSearchLimit      = 10000
+	maxSearchWorkers    = 8
+	maxSearchPending    = 256 // 每个文件最多缓存的待合并结果，缓存满时暂停读取该文件
+	searchProgressLines = 256 // 每读取多少行更新一次文件已读到的日志时间
+)
+
+// SearchOptions 搜索选项
+type SearchOptions struct {
+	Filter    *Filter
+	Glob      string    // 文件名通配符，规则与 IncludePatterns 相同，为空时搜索全部文件
+	ModFrom   time.Time // 文件修改时间下限，零值表示不限
+	ModTo     time.Time // 文件修改时间上限，零值表示不限
+	Limit     int       // 最多返回的结果数，默认 100，最大 10000
+	Workers   int       // 同时读取的文件数，默认为 CPU 数，最多 8
+	Recursive bool      // 是否包含子目录中的文件
+}
+
+// SearchResult 搜索结果
+type SearchResult struct {
+	File  string   `json:"file"` // 所在文件
+	Entry LogEntry `json:"entry"`
+}
+
+// SearchSummary 搜索完成后的统计
+type SearchSummary struct {
+	Files   int               `json:"files"`            // 搜索的文件数
+	Matches int               `json:"matches"`          // 返回的结果数
+	Limited bool              `json:"limited"`          // 是否因达到 Limit 而提前结束
+	Errors  map[string]string `json:"errors,omitempty"` // 读取失败的文件
+}
+
+// searchHit 文件中的一条结果，按时间及文件内顺序排序
+type searchHit struct {
+	result SearchResult
+	time   time.Time
+}
+
+// 文件的读取状态
+const (
+	searchQueued  = iota // 尚未开始读取
+	searchRunning        // 正在读取
+	searchPaused         // 待合并的结果已满，让出 worker，之后从 offset 继续读取
+	searchDone           // 已读完、达到 Limit 或读取失败
+)
+
+// searchFile 一个文件的搜索状态，由读取该文件的 worker 追加结果
+type searchFile struct {
+	name    string
+	hits    []searchHit // 待合并的结果
+	state   int
+	offset  int64     // 下次读取的位置
+	matches int       // 已读取的结果数
+	latest  time.Time // 已读到的最晚日志时间，之后的结果都不会早于它
+	err     error
+}
+
+// ready 判断文件能否交给 worker 读取，暂停的文件在待合并的结果减半后继续
+func (f *searchFile) ready() bool {
+	return f.state == searchQueued || (f.state == searchPaused && len(f.hits) <= maxSearchPending/2)
+}
+
+// advance 更新已读到的日志时间
+func (f *searchFile) advance(t time.Time) {
+	if t.After(f.latest) {
+		f.latest = t
+	}
+}
+
+// reached 判断文件之后的结果是否都不早于 t，尚未开始读取的文件无法判断
+func (f *searchFile) reached(t time.Time) bool {
+	return f.state != searchQueued && !f.latest.Before(t)
+}
+
+// Search 并行搜索日志源中的文件，结果按日志时间顺序依次交给 fn，达到 Limit、ctx 取消或 fn 返回错误时提前结束。
+// 各文件内的日志视为按时间顺序排列，每个文件最多读取 Limit 条结果；一条结果不晚于其余每个未读完文件已读到的时间时即可发送，
+// 尚未开始读取的文件需要等待 worker。每个文件最多缓存 maxSearchPending 条待合并的结果，缓存满时让出 worker 给其他文件，
+// 之后从暂停处继续读取。时间无法解析的日志排在最前
+func (s *Source) Search(ctx context.Context, opts SearchOptions, fn func(SearchResult) error) (*SearchSummary, error) {
+	if err := s.authorize(PermView, ""); err != nil {
+		return nil, err
+	}
+	if opts.Glob != "" {
+		if _, err := path.Match(opts.Glob, ""); err != nil {
+			return nil, fmt.Errorf("%w: glob %q", ErrInvalidPath, opts.Glob)
+		}
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = defaultSearchLimit
+	}
+	if opts.Limit > maxSearchLimit {
+		opts.Limit = maxSearchLimit
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = min(runtime.GOMAXPROCS(0), maxSearchWorkers)
+	}
+	// 修改时间早于 From 的文件不会包含之后写入的日志
+	if f := opts.Filter; f != nil && !f.From.IsZero() && (opts.ModFrom.IsZero() || opts.ModFrom.Before(f.From)) {
+		opts.ModFrom = f.From
+	}
+
+	var files []*searchFile
+	err := s.walkLogFiles(ListOptions{Recursive: opts.Recursive}, func(name string, info fs.FileInfo) error {
+		if opts.Glob != "" && !matchName(opts.Glob, name) {
+			return nil
+		}
+		if (!opts.ModFrom.IsZero() && info.ModTime().Before(opts.ModFrom)) || (!opts.ModTo.IsZero() && info.ModTime().After(opts.ModTo)) {
+			return nil
+		}
+		if s.Can(PermView, name) {
+			files = append(files, &searchFile{name: name})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	var (
+		mu     sync.Mutex
+		cond   = sync.NewCond(&mu)
+		notify = make(chan struct{}, 1)
+		wg     sync.WaitGroup
+	)
+	wake := func() {
+		select {
+		case notify <- struct{}{}:
+		default:
+		}
+	}
+	// 取消时唤醒等待中的 worker
+	stop := context.AfterFunc(ctx, func() {
+		mu.Lock()
+		cond.Broadcast()
+		mu.Unlock()
+	})
+	defer stop()
+	// other 返回除 file 之外可以读取的文件，调用方需持有锁
+	other := func(file *searchFile) *searchFile {
+		for _, f := range files {
+			if f != file && f.ready() {
+				return f
+			}
+		}
+		return nil
+	}
+	// next 等待下一个可以读取的文件，没有排队或暂停的文件或已取消时返回 nil，调用方需持有锁
+	next := func() *searchFile {
+		for ctx.Err() == nil {
+			if f := other(nil); f != nil {
+				return f
+			}
+			paused := false
+			for _, f := range files {
+				paused = paused || f.state == searchPaused
+			}
+			if !paused {
+				return nil
+			}
+			cond.Wait()
+		}
+		return nil
+	}
+	for i := 0; i < min(opts.Workers, len(files)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mu.Lock()
+			defer mu.Unlock()
+			for file := next(); file != nil; file = next() {
+				file.state = searchRunning
+				mu.Unlock()
+				err := s.searchFile(ctx, file.name, opts.Filter, file.offset, func(hit searchHit, end int64) bool {
+					mu.Lock()
+					defer mu.Unlock()
+					file.hits = append(file.hits, hit)
+					file.advance(hit.time)
+					file.offset = end
+					file.matches++
+					wake()
+					for len(file.hits) >= maxSearchPending && ctx.Err() == nil {
+						// 缓存已满：有其他文件等待读取时让出 worker，否则等待合并取走结果
+						if other(file) != nil {
+							file.state = searchPaused
+							return false
+						}
+						cond.Wait()
+					}
+					return file.matches < opts.Limit && ctx.Err() == nil
+				}, func(t time.Time) {
+					mu.Lock()
+					file.advance(t)
+					mu.Unlock()
+					wake()
+				})
+				mu.Lock()
+				if file.state != searchPaused || err != nil {
+					file.state, file.err = searchDone, err
+				}
+				cond.Broadcast()
+				wake()
+			}
+		}()
+	}
+	// 提前结束时通知 worker 停止读取，并等待其退出
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	summary := &SearchSummary{Files: len(files)}
+	for {
+		mu.Lock()
+		batch, finished := mergeHits(files, opts.Limit-summary.Matches)
+		if len(batch) > 0 {
+			// 缓存已满或暂停的文件可以继续读取
+			cond.Broadcast()
+		}
+		mu.Unlock()
+
+		for _, hit := range batch {
+			if err := fn(hit.result); err != nil {
+				return nil, err
+			}
+			summary.Matches++
+		}
+		if summary.Matches >= opts.Limit {
+			summary.Limited = true
+			break
+		}
+		if finished {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return summary, ctx.Err()
+		case <-notify:
+		}
+	}
+
+	cancel()
+	wg.Wait()
+	for _, file := range files {
+		if file.err != nil {
+			if summary.Errors == nil {
+				summary.Errors = make(map[string]string)
+			}
+			summary.Errors[file.name] = file.err.Error()
+		}
+	}
+	return summary, nil
+}
+
+// mergeHits 取出可以确定顺序的结果，最多 limit 条：待合并结果中最早的一条不晚于其余每个没有待合并结果、
+// 未读完的文件已读到的时间时即可确定。全部文件读完且结果取完时 finished 为 true。调用方需持有锁
+func mergeHits(files []*searchFile, limit int) (batch []searchHit, finished bool) {
+	for len(batch) < limit {
+		var best *searchFile
+		open := false
+		for _, f := range files {
+			if len(f.hits) > 0 {
+				if best == nil || f.hits[0].time.Before(best.hits[0].time) {
+					best = f
+				}
+			} else if f.state != searchDone {
+				open = true
+			}
+		}
+		if best == nil {
+			return batch, !open
+		}
+		head := best.hits[0]
+		for _, f := range files {
+			if len(f.hits) == 0 && f.state != searchDone && !f.reached(head.time) {
+				return batch, false
+			}
+		}
+		batch = append(batch, head)
+		best.hits[0] = searchHit{}
+		best.hits = best.hits[1:]
+	}
+	return batch, false
+}
+
+// searchFile 从 from 偏移处读取一个文件，将满足条件的日志及其结束位置交给 add，add 返回 false 时停止；
+// 每读取 searchProgressLines 行通过 progress 报告已读到的日志时间
+func (s *Source) searchFile(ctx context.Context, name string, filter *Filter, from int64, add func(hit searchHit, end int64) bool, progress func(time.Time)) error {
+	file, path, err := s.open(PermView, name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	parser := &progressParser{LineParser: s.lv.fileParser(name, file), fn: progress}
+	return s.scanFiltered(ctx, file, path, parser, filter, from, func(entry *LogEntry, _, end int64) bool {
+		t, _ := ParseTime(entry.Time)
+		return add(searchHit{result: SearchResult{File: name, Entry: *entry}, time: t}, end)
+	})
+}
+
+// progressParser 解析时记录读取进度，不满足过滤条件的日志同样说明文件已读到该时间
+type progressParser struct {
+	LineParser
+	lines int
+	fn    func(time.Time)
+}
+
+func (p *progressParser) Parse(line []byte) (LogEntry, bool) {
+	entry, ok := p.LineParser.Parse(line)
+	if p.lines++; ok && p.lines%searchProgressLines == 0 {
+		if t, err := ParseTime(entry.Time); err == nil {
+			p.fn(t)
+		}
+	}
+	return entry, ok
+}
+
+// SearchHandler 在日志源的全部文件中搜索，结果按时间顺序以 NDJSON 流式返回，每行为 {"file","entry"}，
+// 最后一行为 {"summary"}。参数：过滤参数（见 ParseFilter）、limit、glob、recursive 以及按文件修改时间选择文件的 mtimeFrom/mtimeTo
+func (lv *LogViewer) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := ParseFilter(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts := SearchOptions{Filter: filter, Glob: query.Get("glob"), Recursive: boolParam(query.Get("recursive"))}
+	if s := query.Get("limit"); s != "" {
+		if opts.Limit, err = strconv.Atoi(s); err != nil || opts.Limit <= 0 {
+			http.Error(w, "invalid limit: "+s, http.StatusBadRequest)
+			return
+		}
+	}
+	for key, t := range map[string]*time.Time{"mtimeFrom": &opts.ModFrom, "mtimeTo": &opts.ModTo} {
+		if s := query.Get(key); s != "" {
+			if *t, err = ParseTime(s); err != nil {
+				http.Error(w, fmt.Sprintf("invalid %s: %s", key, s), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	src, err := lv.requestSource(r)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	started := false
+	summary, err := src.Search(r.Context(), opts, func(result SearchResult) error {
+		if !started {
+			started = true
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		if err := enc.Encode(result); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		if !started {
+			lv.auditDenied(r, PermView, src, "", err)
+			httpError(w, err)
+		}
+		return
+	}
+	if !started {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Cache-Control", "no-store")
+	}
+	enc.Encode(map[string]interface{}{"summary": summary})
+}
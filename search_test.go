@@ -0,0 +1,207 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/18 05:40:52
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/18 05:40:52
+ * Description: 跨文件搜索测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// searchFiles 时间交错的多个日志文件，第 i 分钟的日志写入 file-(i%5).log
+func searchFiles() map[string]string {
+	files := map[string]string{}
+	for i := 0; i < 50; i++ {
+		name := fmt.Sprintf("file-%d.log", i%5)
+		if i%5 == 4 {
+			name = "sub/" + name
+		}
+		files[name] += fmt.Sprintf(`{"time":"2026-10-01T14:%02d:00Z","level":"INFO","msg":"step %d","request_id":"r%d"}`, i, i, i%2) + "\n"
+	}
+	return files
+}
+
+// collectSearch 返回结果的消息及所在文件
+func collectSearch(t *testing.T, s *Source, opts SearchOptions) ([]string, *SearchSummary) {
+	t.Helper()
+	var got []string
+	summary, err := s.Search(context.Background(), opts, func(r SearchResult) error {
+		got = append(got, r.File+":"+r.Entry.Msg)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	return got, summary
+}
+
+func TestSearch(t *testing.T) {
+	lv := newTestViewer(t, searchFiles(), nil)
+	setModTime(t, lv, "file-0.log", time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC))
+	s := lv.defaultSource()
+
+	// 按时间顺序合并，worker 少于文件数时同样有序
+	for _, workers := range []int{1, 2, 8} {
+		got, summary := collectSearch(t, s, SearchOptions{Recursive: true, Workers: workers, Limit: 1000})
+		if len(got) != 50 || summary.Files != 5 || summary.Matches != 50 || summary.Limited {
+			t.Fatalf("Unexpected results: %d %+v", len(got), summary)
+		}
+		for i, r := range got {
+			file := fmt.Sprintf("file-%d.log", i%5)
+			if i%5 == 4 {
+				file = "sub/" + file
+			}
+			if r != fmt.Sprintf("%s:step %d", file, i) {
+				t.Fatalf("workers=%d: unexpected result %d: %s", workers, i, r)
+			}
+		}
+	}
+
+	// 过滤条件与结果数限制
+	q, _ := ParseQuery(`request_id=r1 AND msg~"step [0-9]$"`)
+	got, summary := collectSearch(t, s, SearchOptions{Filter: &Filter{Query: q}, Limit: 3})
+	if strings.Join(got, ",") != "file-1.log:step 1,file-3.log:step 3,file-0.log:step 5" || !summary.Limited {
+		t.Errorf("Unexpected filtered results: %v %+v", got, summary)
+	}
+
+	// 按文件修改时间及通配符选择文件
+	got, _ = collectSearch(t, s, SearchOptions{ModFrom: time.Date(2026, 9, 2, 0, 0, 0, 0, time.UTC), Glob: "file-[01].log", Limit: 2})
+	if strings.Join(got, ",") != "file-1.log:step 1,file-1.log:step 6" {
+		t.Errorf("Unexpected results: %v", got)
+	}
+
+	// 回调返回错误时提前结束
+	stop := errors.New("stop")
+	n := 0
+	_, err := s.Search(context.Background(), SearchOptions{Recursive: true}, func(SearchResult) error {
+		if n++; n == 2 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || n != 2 {
+		t.Errorf("Expected search to stop after callback error, got %v after %d", err, n)
+	}
+
+	// ctx 取消时结束
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.Search(ctx, SearchOptions{Recursive: true, Filter: &Filter{Contains: "nothing"}}, func(SearchResult) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestSearch_Pending(t *testing.T) {
+	// 每个文件的结果远多于缓存上限，单个 worker 需要暂停并恢复各文件才能按序合并全部结果
+	files := map[string]string{}
+	for i := 0; i < 3*maxSearchPending*4; i++ {
+		name := fmt.Sprintf("file-%d.log", i%3)
+		files[name] += fmt.Sprintf(`{"time":"2026-10-01T%02d:%02d:%02dZ","level":"INFO","msg":"step %d"}`, i/3600, i/60%60, i%60, i) + "\n"
+	}
+	lv := newTestViewer(t, files, nil)
+	for _, workers := range []int{1, 2} {
+		got, summary := collectSearch(t, lv.defaultSource(), SearchOptions{Workers: workers, Limit: maxSearchLimit})
+		if len(got) != 3*maxSearchPending*4 || summary.Limited {
+			t.Fatalf("Workers %d: expected all results, got %d %+v", workers, len(got), summary)
+		}
+		for i, r := range got {
+			if want := fmt.Sprintf("file-%d.log:step %d", i%3, i); r != want {
+				t.Fatalf("Workers %d: expected %s at %d, got %s", workers, want, i, r)
+			}
+		}
+	}
+}
+
+func TestMergeHits(t *testing.T) {
+	at := func(minute int) time.Time { return time.Date(2026, 10, 1, 14, minute, 0, 0, time.UTC) }
+	hit := func(minute int) searchHit { return searchHit{time: at(minute)} }
+
+	// 其余文件已读到的时间不早于最早的结果时即可发送，无需等待这些文件出现结果
+	a := &searchFile{name: "a", hits: []searchHit{hit(1), hit(5)}, state: searchRunning, latest: at(5)}
+	b := &searchFile{name: "b", state: searchRunning, latest: at(3)}
+	batch, finished := mergeHits([]*searchFile{a, b}, 10)
+	if len(batch) != 1 || !batch[0].time.Equal(at(1)) || finished {
+		t.Errorf("Expected only the hit before b's progress, got %d %v", len(batch), finished)
+	}
+
+	// b 读到更晚的时间后剩余结果可以发送
+	b.latest = at(5)
+	if batch, _ = mergeHits([]*searchFile{a, b}, 10); len(batch) != 1 || len(a.hits) != 0 {
+		t.Errorf("Expected the remaining hit, got %d", len(batch))
+	}
+
+	// 尚未开始读取的文件无法判断，需要等待
+	a.hits = []searchHit{hit(6)}
+	c := &searchFile{name: "c", state: searchQueued}
+	if batch, _ = mergeHits([]*searchFile{a, b, c}, 10); len(batch) != 0 {
+		t.Errorf("Expected queued file to block, got %d", len(batch))
+	}
+
+	// 全部读完且结果取完后结束
+	a.state, b.state, c.state = searchDone, searchDone, searchDone
+	if batch, finished = mergeHits([]*searchFile{a, b, c}, 10); len(batch) != 1 || !finished {
+		t.Errorf("Expected final hit and finished, got %d %v", len(batch), finished)
+	}
+}
+
+func TestSearch_Permissions(t *testing.T) {
+	lv := newTestViewer(t, searchFiles(), &Config{
+		Roles:     []Role{{Name: "one", Permissions: []string{PermView}, Files: []string{"file-1.log"}}},
+		UserRoles: map[string][]string{"ann": {"one"}},
+	})
+	got, summary := collectSearch(t, lv.defaultSource().As(&Identity{Name: "ann"}), SearchOptions{Recursive: true})
+	if len(got) != 10 || summary.Files != 1 || !strings.HasPrefix(got[0], "file-1.log:") {
+		t.Errorf("Expected only files in role scope, got %v", got)
+	}
+	if _, err := lv.defaultSource().Search(context.Background(), SearchOptions{}, func(SearchResult) error { return nil }); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected anonymous search to be forbidden, got %v", err)
+	}
+}
+
+func TestSearchHandler(t *testing.T) {
+	lv := newTestViewer(t, searchFiles(), nil)
+	handler := lv.Handler("/log")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/log/search?recursive=1&limit=4&attr.request_id=r0", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("Unexpected response: %d %v", w.Code, w.Header())
+	}
+	var lines []map[string]json.RawMessage
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var line map[string]json.RawMessage
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("Invalid line %s: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 5 || string(lines[1]["file"]) != `"file-2.log"` {
+		t.Fatalf("Unexpected lines: %s", w.Body.String())
+	}
+	var summary SearchSummary
+	if err := json.Unmarshal(lines[4]["summary"], &summary); err != nil || summary.Matches != 4 || !summary.Limited {
+		t.Errorf("Unexpected summary: %s", lines[4]["summary"])
+	}
+
+	for _, query := range []string{"limit=0", "mtimeFrom=yesterday", "q=level%3E%3D", "glob=["} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/log/search?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
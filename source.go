@@ -0,0 +1,192 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 17:12:36
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 17:12:36
+ * Description: 多日志源
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultSourceName 未配置 Sources 时由 LogDir 生成的日志源名称
+const DefaultSourceName = "default"
+
+// ErrUnknownSource 日志源不存在
+var ErrUnknownSource = errors.New("unknown source")
+
+// ErrDuplicateSource 多个日志源使用同一名称
+var ErrDuplicateSource = errors.New("duplicate source name")
+
+// SourceConfig 日志源配置
+type SourceConfig struct {
+	Name            string   `json:"name"`            // 名称，用于请求参数 source
+	Dir             string   `json:"-"`               // 日志目录路径
+	IncludePatterns []string `json:"includePatterns"` // 只显示匹配的文件，为空时显示全部
+	ExcludePatterns []string `json:"excludePatterns"` // 隐藏匹配的文件或目录
+	EnableDelete    bool     `json:"enableDelete"`    // 是否启用删除功能（仍需 DevMode），配置 Roles 后不再使用
+	EnableClear     bool     `json:"enableClear"`     // 是否启用清除功能（仍需 DevMode），配置 Roles 后不再使用
+	EnableExport    bool     `json:"enableExport"`    // 是否启用导出功能，配置 Roles 后不再使用
+}
+
+// Source 日志源，文件名均为相对其目录的路径
+type Source struct {
+	lv       *LogViewer
+	config   SourceConfig
+	identity *Identity   // 操作的用户，见 As
+	memory   *RingBuffer // 内存缓冲区，不为 nil 时日志源只有一个虚拟文件 MemoryFileName
+}
+
+// Name 返回日志源名称
+func (s *Source) Name() string {
+	return s.config.Name
+}
+
+// Config 返回日志源配置
+func (s *Source) Config() SourceConfig {
+	return s.config
+}
+
+// Sources 返回全部日志源，未配置 Sources 时为由 LogDir 生成的默认日志源，
+// 配置了 Memory 时最后为内存日志源，此时 LogDir 可以为空
+func (lv *LogViewer) Sources() []*Source {
+	var sources []*Source
+	if len(lv.config.Sources) == 0 {
+		if lv.config.LogDir != "" || lv.config.Memory == nil {
+			sources = append(sources, &Source{lv: lv, config: SourceConfig{
+				Name:         DefaultSourceName,
+				Dir:          lv.config.LogDir,
+				EnableDelete: lv.config.EnableDelete,
+				EnableClear:  lv.config.EnableClear,
+				EnableExport: lv.config.EnableExport,
+			}})
+		}
+	}
+	for _, config := range lv.config.Sources {
+		sources = append(sources, &Source{lv: lv, config: config})
+	}
+	if lv.config.Memory != nil {
+		sources = append(sources, lv.memorySource())
+	}
+	return sources
+}
+
+// Source 按名称获取日志源，名称为空时返回第一个日志源，多个日志源同名时返回 ErrDuplicateSource
+func (lv *LogViewer) Source(name string) (*Source, error) {
+	sources := lv.Sources()
+	if name == "" {
+		return sources[0], nil
+	}
+	var found *Source
+	for _, s := range sources {
+		if s.config.Name != name {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicateSource, name)
+		}
+		found = s
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSource, name)
+	}
+	return found, nil
+}
+
+// defaultSource 返回默认日志源
+func (lv *LogViewer) defaultSource() *Source {
+	return lv.Sources()[0]
+}
+
+// hidden 判断文件是否被 Config 或日志源的通配符规则隐藏，所在目录被排除时同样视为隐藏，审计文件总是隐藏，
+// 内存日志源只有 MemoryFileName
+func (s *Source) hidden(name string) bool {
+	if s.memory != nil {
+		return name != MemoryFileName
+	}
+	for dir := parentDir(name); dir != ""; dir = parentDir(dir) {
+		if s.excluded(dir) {
+			return true
+		}
+	}
+	if s.excluded(name) || s.lv.isAuditFile(filepath.Join(s.config.Dir, filepath.FromSlash(name))) {
+		return true
+	}
+	return !included(s.lv.config.IncludePatterns, name) || !included(s.config.IncludePatterns, name)
+}
+
+// excluded 判断文件或目录是否匹配排除规则
+func (s *Source) excluded(name string) bool {
+	for _, patterns := range [][]string{s.lv.config.ExcludePatterns, s.config.ExcludePatterns} {
+		for _, pattern := range patterns {
+			if matchName(strings.TrimSuffix(pattern, "/"), name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// included 判断文件是否匹配包含规则，没有规则时视为匹配
+func included(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if matchName(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetLogFiles 获取默认日志源的日志文件列表
+func (lv *LogViewer) GetLogFiles() ([]string, error) {
+	return lv.defaultSource().GetLogFiles()
+}
+
+// ListLogFiles 获取默认日志源的日志文件信息列表
+func (lv *LogViewer) ListLogFiles(opts ListOptions) ([]LogFile, error) {
+	return lv.defaultSource().ListLogFiles(opts)
+}
+
+// GetLogTree 获取默认日志源的目录树
+func (lv *LogViewer) GetLogTree(opts ListOptions) (*LogTree, error) {
+	return lv.defaultSource().GetLogTree(opts)
+}
+
+// GetLogContent 获取默认日志源中的日志内容
+func (lv *LogViewer) GetLogContent(filename string, filters ...*Filter) ([]LogEntry, error) {
+	return lv.defaultSource().GetLogContent(filename, filters...)
+}
+
+// GetLogPage 分页获取默认日志源中的日志内容
+func (lv *LogViewer) GetLogPage(filename string, q PageQuery) (*LogPage, error) {
+	return lv.defaultSource().GetLogPage(filename, q)
+}
+
+// Tail 跟踪默认日志源中的日志文件
+func (lv *LogViewer) Tail(ctx context.Context, filename string, filter *Filter, fn func(TailEvent) error) error {
+	return lv.defaultSource().Tail(ctx, filename, filter, fn)
+}
+
+// DeleteAllLogs 删除默认日志源中的所有日志文件
+func (lv *LogViewer) DeleteAllLogs() error {
+	return lv.defaultSource().DeleteAllLogs()
+}
+
+// ClearFileContent 清空默认日志源中的文件内容
+func (lv *LogViewer) ClearFileContent(filename string) error {
+	return lv.defaultSource().ClearFileContent(filename)
+}
+
+func (lv *LogViewer) ExportFile(filename string) error {
+	return lv.defaultSource().ExportFile(filename)
+}
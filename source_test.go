@@ -0,0 +1,152 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 17:48:20
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 17:48:20
+ * Description: 多日志源测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newSourcesViewer 创建包含 api、worker 两个日志源的 LogViewer
+func newSourcesViewer(t *testing.T) (*LogViewer, string, string) {
+	t.Helper()
+	apiDir, workerDir := t.TempDir(), t.TempDir()
+	appendLine(t, filepath.Join(apiDir, "api.log"), `{"level":"INFO","msg":"api"}`+"\n")
+	appendLine(t, filepath.Join(workerDir, "worker.log"), `{"level":"INFO","msg":"worker"}`+"\n")
+	appendLine(t, filepath.Join(workerDir, "worker.pid"), "1234\n")
+	os.MkdirAll(filepath.Join(workerDir, "archive"), 0755)
+	appendLine(t, filepath.Join(workerDir, "archive", "old.log"), `{"level":"INFO","msg":"old"}`+"\n")
+
+	lv := New(&Config{
+		DevMode: true,
+		Sources: []SourceConfig{
+			{Name: "api", Dir: apiDir, EnableExport: true},
+			{Name: "worker", Dir: workerDir, ExcludePatterns: []string{"*.pid", "archive/"}, EnableClear: true},
+		},
+	})
+	return lv, apiDir, workerDir
+}
+
+func TestSources(t *testing.T) {
+	lv, _, _ := newSourcesViewer(t)
+
+	if s, err := lv.Source(""); err != nil || s.Name() != "api" {
+		t.Errorf("Expected first source as default, got %v %v", s, err)
+	}
+	if _, err := lv.Source("cron"); !errors.Is(err, ErrUnknownSource) {
+		t.Errorf("Expected ErrUnknownSource, got %v", err)
+	}
+
+	worker, _ := lv.Source("worker")
+	files, err := worker.ListLogFiles(ListOptions{Recursive: true})
+	if err != nil || len(files) != 1 || files[0].Name != "worker.log" {
+		t.Fatalf("Unexpected worker files: %v %v", files, err)
+	}
+	for _, name := range []string{"worker.pid", "archive/old.log"} {
+		if _, err := worker.GetLogContent(name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("GetLogContent(%q) = %v, want fs.ErrNotExist", name, err)
+		}
+	}
+
+	// 各日志源的开关互相独立
+	api, _ := lv.Source("api")
+	if err := api.ClearFileContent("api.log"); err == nil {
+		t.Error("Expected clear to be disabled for api source")
+	}
+	if err := worker.ClearFileContent("worker.log"); err != nil {
+		t.Errorf("ClearFileContent failed: %v", err)
+	}
+}
+
+func TestSourceHandlers(t *testing.T) {
+	lv, _, _ := newSourcesViewer(t)
+
+	req := httptest.NewRequest("GET", "/log/getLogFilesList?source=worker", nil)
+	w := httptest.NewRecorder()
+	lv.GetFilesHandler(w, req)
+	var files struct {
+		Files []string `json:"files"`
+	}
+	json.NewDecoder(w.Body).Decode(&files)
+	if strings.Join(files.Files, ",") != "worker.log" {
+		t.Errorf("Unexpected files: %v", files.Files)
+	}
+
+	req = httptest.NewRequest("GET", "/log/getFileContent?source=api&name=api.log", nil)
+	w = httptest.NewRecorder()
+	lv.GetContentHandler(w, req)
+	var content struct {
+		Data []LogEntry `json:"data"`
+	}
+	json.NewDecoder(w.Body).Decode(&content)
+	if len(content.Data) != 1 || content.Data[0].Msg != "api" {
+		t.Errorf("Unexpected content: %+v", content.Data)
+	}
+
+	req = httptest.NewRequest("GET", "/log/getFileContent?source=cron&name=api.log", nil)
+	w = httptest.NewRecorder()
+	lv.GetContentHandler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status BadRequest for unknown source, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/log/getFileContent?source=worker&name=worker.pid", nil)
+	w = httptest.NewRecorder()
+	lv.GetContentHandler(w, req)
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "does not exist") {
+		t.Errorf("Expected hidden file to be reported as missing, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/log/exportFile?source=worker&name=worker.log", nil)
+	w = httptest.NewRecorder()
+	lv.ExportFileHandler(w, req)
+	var export struct {
+		Code int `json:"code"`
+	}
+	json.NewDecoder(w.Body).Decode(&export)
+	if export.Code != 3001 {
+		t.Errorf("Expected export to be disabled for worker source, got %d", export.Code)
+	}
+
+	form := url.Values{"source": {"worker"}, "name": {"worker.log"}}
+	req = httptest.NewRequest("POST", "/log/clearFileContent", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	lv.ClearFileContentHandler(w, req)
+	var clear struct {
+		Code int `json:"code"`
+	}
+	json.NewDecoder(w.Body).Decode(&clear)
+	if clear.Code != 200 {
+		t.Errorf("Expected clear to succeed for worker source, got %d", clear.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/log/getSources", nil)
+	w = httptest.NewRecorder()
+	lv.GetSourcesHandler(w, req)
+	var sources struct {
+		Data []struct {
+			Name         string `json:"name"`
+			EnableClear  bool   `json:"enableClear"`
+			EnableExport bool   `json:"enableExport"`
+		} `json:"data"`
+	}
+	json.NewDecoder(w.Body).Decode(&sources)
+	if len(sources.Data) != 2 || sources.Data[0].Name != "api" || !sources.Data[0].EnableExport || !sources.Data[1].EnableClear {
+		t.Errorf("Unexpected sources: %+v", sources.Data)
+	}
+}
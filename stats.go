@@ -0,0 +1,447 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/18 07:20:44
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/18 07:20:44
+ * Description: 日志统计，按时间桶统计各级别数量，以及出现最多的消息模板和属性值
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultStatsBuckets = 60
+	maxStatsBuckets     = 10000
+	defaultStatsTop     = 10
+	maxStatsTop         = 100
+	maxStatsDistinct    = 10000 // 消息模板及每个属性最多保留的计数项，超出时丢弃出现次数最少的项
+	maxTemplateLength   = 512   // 生成模板前截断过长的消息
+)
+
+// statsIntervals 自动选择的时间桶宽度，每一项都是前一项的整数倍，便于合并
+var statsIntervals = []time.Duration{
+	time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+	time.Minute, 5 * time.Minute, 10 * time.Minute, 30 * time.Minute,
+	time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour,
+	24 * time.Hour, 7 * 24 * time.Hour,
+}
+
+// ErrTooManyBuckets 指定的时间桶宽度过小，时间范围内的桶数超过上限
+var ErrTooManyBuckets = errors.New("too many buckets")
+
+// templatePattern 消息中的可变部分：引号内的文本、UUID、十六进制数以及数字开头（可带单位，如 5s）或十六进制中含数字的单词
+var templatePattern = regexp.MustCompile(`"[^"]*"|'[^']*'|\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b|\b0[xX][0-9a-fA-F]+\b|\b[0-9a-fA-F]*[0-9][0-9a-zA-Z]*\b`)
+
+// StatsOptions 统计选项
+type StatsOptions struct {
+	Filter    *Filter
+	Interval  time.Duration // 时间桶宽度，为 0 时按 Buckets 自动选择
+	Buckets   int           // 自动选择宽度时的最大桶数，默认 60
+	Top       int           // 消息模板及属性值各返回的条数，默认 10，最大 100
+	Attrs     []string      // 统计取值分布的属性键，分组属性用 "." 连接
+	Glob      string        // 统计全部文件时的文件名通配符
+	Recursive bool          // 统计全部文件时是否包含子目录
+}
+
+// StatsBucket 一个时间桶，范围为 [Start, End)
+type StatsBucket struct {
+	Start  time.Time      `json:"start"`
+	End    time.Time      `json:"end"`
+	Total  int            `json:"total"`
+	Levels map[string]int `json:"levels"`
+}
+
+// StatsCount 消息模板或属性值的出现次数
+type StatsCount struct {
+	Value   string `json:"value"`
+	Count   int    `json:"count"`
+	Example string `json:"example,omitempty"` // 消息模板对应的一条原始消息
+}
+
+// LogStats 统计结果
+type LogStats struct {
+	Files     int                     `json:"files"`   // 统计的文件数
+	Total     int                     `json:"total"`   // 满足过滤条件的记录数
+	Untimed   int                     `json:"untimed"` // 时间无法解析、不计入时间桶的记录数
+	Levels    map[string]int          `json:"levels"`
+	Interval  string                  `json:"interval"` // 时间桶宽度，如 "5m0s"
+	Buckets   []StatsBucket           `json:"buckets"`
+	Templates []StatsCount            `json:"templates"` // 出现最多的消息模板，可变部分替换为 <*>
+	Attrs     map[string][]StatsCount `json:"attrs,omitempty"`
+	Errors    map[string]string       `json:"errors,omitempty"` // 统计全部文件时读取失败的文件
+}
+
+// Stats 统计文件中满足过滤条件的日志，name 为空时统计日志源中的全部文件（只包含用户可查看的文件）。
+// 时间桶连续排列，包含没有日志的桶；指定了过滤条件的 from 和 to 时覆盖整个时间范围
+func (s *Source) Stats(ctx context.Context, name string, opts StatsOptions) (*LogStats, error) {
+	if opts.Interval < 0 || (opts.Interval > 0 && opts.Interval < time.Second) {
+		return nil, fmt.Errorf("%w: interval must be at least 1s", ErrTooManyBuckets)
+	}
+	if opts.Buckets <= 0 {
+		opts.Buckets = defaultStatsBuckets
+	}
+	opts.Buckets = min(opts.Buckets, maxStatsBuckets)
+	if opts.Top <= 0 {
+		opts.Top = defaultStatsTop
+	}
+	opts.Top = min(opts.Top, maxStatsTop)
+
+	names := []string{name}
+	if name == "" {
+		if err := s.authorize(PermView, ""); err != nil {
+			return nil, err
+		}
+		if opts.Glob != "" {
+			if _, err := path.Match(opts.Glob, ""); err != nil {
+				return nil, fmt.Errorf("%w: glob %q", ErrInvalidPath, opts.Glob)
+			}
+		}
+		names = nil
+		err := s.walkLogFiles(ListOptions{Recursive: opts.Recursive}, func(name string, _ fs.FileInfo) error {
+			if (opts.Glob == "" || matchName(opts.Glob, name)) && s.Can(PermView, name) {
+				names = append(names, name)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	acc := newStatsAccumulator(opts)
+	if acc.err != nil {
+		return nil, acc.err
+	}
+	stats := &LogStats{Files: len(names)}
+	for _, n := range names {
+		err := s.statsFile(ctx, n, opts.Filter, acc)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if errors.Is(err, ErrTooManyBuckets) || (err != nil && name != "") {
+			return nil, err
+		}
+		if err != nil {
+			if stats.Errors == nil {
+				stats.Errors = make(map[string]string)
+			}
+			stats.Errors[n] = err.Error()
+		}
+	}
+	acc.result(stats, opts.Top)
+	return stats, nil
+}
+
+// statsFile 统计一个文件，有持久索引时只读取候选记录
+func (s *Source) statsFile(ctx context.Context, name string, filter *Filter, acc *statsAccumulator) error {
+	file, path, err := s.open(PermView, name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	parser := s.lv.fileParser(name, file)
+	err = s.scanFiltered(ctx, file, path, parser, filter, 0, func(entry *LogEntry, _, _ int64) bool {
+		acc.add(entry)
+		return acc.err == nil
+	})
+	if err == nil {
+		err = acc.err
+	}
+	return err
+}
+
+// statsAccumulator 统计的中间状态
+type statsAccumulator struct {
+	hist      histogram
+	total     int
+	untimed   int
+	levels    map[string]int
+	templates counter
+	attrs     map[string]*counter
+	err       error
+}
+
+func newStatsAccumulator(opts StatsOptions) *statsAccumulator {
+	acc := &statsAccumulator{
+		hist:   histogram{interval: opts.Interval, auto: opts.Interval == 0, limit: opts.Buckets, counts: make(map[int64]map[string]int)},
+		levels: make(map[string]int),
+		attrs:  make(map[string]*counter),
+	}
+	if acc.hist.auto {
+		acc.hist.interval = statsIntervals[0]
+	}
+	for _, key := range opts.Attrs {
+		acc.attrs[key] = &counter{}
+	}
+	// 查询的时间范围完整时覆盖整个范围，便于在时间轴上看出没有日志的时段
+	if f := opts.Filter; f != nil && !f.From.IsZero() && !f.To.IsZero() && f.From.Before(f.To) {
+		if acc.hist.auto {
+			for _, iv := range statsIntervals {
+				acc.hist.interval = iv
+				if int(f.To.Sub(f.From)/iv) < acc.hist.limit {
+					break
+				}
+			}
+		}
+		if acc.err = acc.hist.extend(f.From); acc.err == nil {
+			acc.err = acc.hist.extend(f.To.Add(-1))
+		}
+	}
+	return acc
+}
+
+// add 统计一条记录
+func (acc *statsAccumulator) add(entry *LogEntry) {
+	level := levelName(entry.Level)
+	acc.total++
+	acc.levels[level]++
+	if t, err := ParseTime(entry.Time); err == nil {
+		if acc.err = acc.hist.extend(t); acc.err != nil {
+			return
+		}
+		acc.hist.count(t, level)
+	} else {
+		acc.untimed++
+	}
+
+	msg := entry.Msg
+	if len(msg) > maxTemplateLength {
+		msg = strings.ToValidUTF8(msg[:maxTemplateLength], "")
+	}
+	acc.templates.add(messageTemplate(msg), msg)
+	for key, c := range acc.attrs {
+		if v, ok := lookupAttr(entry.Attrs, key); ok {
+			switch v.(type) {
+			case string, json.Number, bool, float64:
+				c.add(fmt.Sprint(v), "")
+			}
+		}
+	}
+}
+
+// result 生成统计结果
+func (acc *statsAccumulator) result(stats *LogStats, top int) {
+	stats.Total, stats.Untimed, stats.Levels = acc.total, acc.untimed, acc.levels
+	stats.Interval = acc.hist.interval.String()
+	stats.Buckets = acc.hist.buckets()
+	stats.Templates = acc.templates.top(top)
+	if len(acc.attrs) > 0 {
+		stats.Attrs = make(map[string][]StatsCount, len(acc.attrs))
+		for key, c := range acc.attrs {
+			stats.Attrs[key] = c.top(top)
+		}
+	}
+}
+
+// messageTemplate 将消息中的可变部分替换为 <*>，如 "order 42 failed" 转为 "order <*> failed"
+func messageTemplate(msg string) string {
+	return templatePattern.ReplaceAllString(msg, "<*>")
+}
+
+// histogram 按时间桶统计各级别数量，桶的序号为时间除以宽度向下取整
+type histogram struct {
+	interval time.Duration
+	auto     bool // 桶数超过 limit 时自动增大宽度
+	limit    int
+	lo, hi   int64 // 已出现的最小及最大桶序号
+	started  bool
+	counts   map[int64]map[string]int
+}
+
+// extend 将时间加入统计范围，自动模式下桶数超过上限时增大宽度，否则超过 maxStatsBuckets 时返回 ErrTooManyBuckets
+func (h *histogram) extend(t time.Time) error {
+	for {
+		key := floorDiv(t.UnixNano(), int64(h.interval))
+		lo, hi := key, key
+		if h.started {
+			lo, hi = min(h.lo, key), max(h.hi, key)
+		}
+		if h.auto && hi-lo >= int64(h.limit) {
+			if next := h.nextInterval(); next > 0 {
+				h.coarsen(next)
+				continue
+			}
+		}
+		if hi-lo >= maxStatsBuckets {
+			return fmt.Errorf("%w: more than %d buckets of %s", ErrTooManyBuckets, maxStatsBuckets, h.interval)
+		}
+		h.lo, h.hi, h.started = lo, hi, true
+		return nil
+	}
+}
+
+// count 计入一条记录，调用前需先 extend
+func (h *histogram) count(t time.Time, level string) {
+	key := floorDiv(t.UnixNano(), int64(h.interval))
+	levels := h.counts[key]
+	if levels == nil {
+		levels = make(map[string]int)
+		h.counts[key] = levels
+	}
+	levels[level]++
+}
+
+// nextInterval 返回下一个可选的宽度，已是最大宽度时返回 0
+func (h *histogram) nextInterval() time.Duration {
+	for _, iv := range statsIntervals {
+		if iv > h.interval {
+			return iv
+		}
+	}
+	return 0
+}
+
+// coarsen 将已有的桶合并为更宽的桶
+func (h *histogram) coarsen(interval time.Duration) {
+	rekey := func(key int64) int64 {
+		return floorDiv(key*int64(h.interval), int64(interval))
+	}
+	counts := make(map[int64]map[string]int, len(h.counts))
+	for key, levels := range h.counts {
+		nk := rekey(key)
+		merged := counts[nk]
+		if merged == nil {
+			merged = make(map[string]int)
+			counts[nk] = merged
+		}
+		for level, n := range levels {
+			merged[level] += n
+		}
+	}
+	h.lo, h.hi = rekey(h.lo), rekey(h.hi)
+	h.interval, h.counts = interval, counts
+}
+
+// buckets 返回从最早到最晚的全部时间桶
+func (h *histogram) buckets() []StatsBucket {
+	buckets := []StatsBucket{}
+	if !h.started {
+		return buckets
+	}
+	for key := h.lo; key <= h.hi; key++ {
+		start := time.Unix(0, key*int64(h.interval)).UTC()
+		bucket := StatsBucket{Start: start, End: start.Add(h.interval), Levels: h.counts[key]}
+		if bucket.Levels == nil {
+			bucket.Levels = map[string]int{}
+		}
+		for _, n := range bucket.Levels {
+			bucket.Total += n
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets
+}
+
+// floorDiv 向下取整的整数除法
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
+
+// counter 统计出现次数，计数项超过 maxStatsDistinct 时逐步丢弃次数最少的项，因此结果为近似值
+type counter struct {
+	counts map[string]*StatsCount
+	floor  int // 已丢弃的项的最大次数
+}
+
+// add 计入一次出现
+func (c *counter) add(value, example string) {
+	if item := c.counts[value]; item != nil {
+		item.Count++
+		return
+	}
+	if c.counts == nil {
+		c.counts = make(map[string]*StatsCount)
+	}
+	for len(c.counts) >= maxStatsDistinct {
+		c.floor++
+		for k, item := range c.counts {
+			if item.Count <= c.floor {
+				delete(c.counts, k)
+			}
+		}
+	}
+	c.counts[value] = &StatsCount{Value: value, Count: 1, Example: example}
+}
+
+// top 返回出现次数最多的 n 项，次数相同时按值排序
+func (c *counter) top(n int) []StatsCount {
+	items := make([]StatsCount, 0, len(c.counts))
+	for _, item := range c.counts {
+		items = append(items, *item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Value < items[j].Value
+	})
+	return items[:min(n, len(items))]
+}
+
+// StatsHandler 统计日志，返回 LogStats。参数：name（为空时统计全部文件，可配合 glob、recursive）、过滤参数（见 ParseFilter）、
+// interval（时间桶宽度，如 5m，为空或 auto 时自动选择）、buckets（自动选择时的最大桶数）、top 以及逗号分隔的属性键 keys
+func (lv *LogViewer) StatsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := ParseFilter(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts := StatsOptions{Filter: filter, Glob: query.Get("glob"), Recursive: boolParam(query.Get("recursive"))}
+	if s := query.Get("interval"); s != "" && s != "auto" {
+		if opts.Interval, err = time.ParseDuration(s); err != nil || opts.Interval < time.Second {
+			http.Error(w, "invalid interval: "+s, http.StatusBadRequest)
+			return
+		}
+	}
+	for key, n := range map[string]*int{"buckets": &opts.Buckets, "top": &opts.Top} {
+		if s := query.Get(key); s != "" {
+			if *n, err = strconv.Atoi(s); err != nil || *n <= 0 {
+				http.Error(w, fmt.Sprintf("invalid %s: %s", key, s), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	for _, key := range strings.Split(query.Get("keys"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			opts.Attrs = append(opts.Attrs, key)
+		}
+	}
+	src, err := lv.requestSource(r)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+
+	filename := query.Get("name")
+	stats, err := src.Stats(r.Context(), filename, opts)
+	if err != nil {
+		lv.auditDenied(r, PermView, src, filename, err)
+		httpError(w, err)
+		return
+	}
+	respondJSON(w, map[string]interface{}{
+		"code": 200,
+		"data": stats,
+		"msg":  "success",
+	})
+}
@@ -0,0 +1,152 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/18 07:52:10
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/18 07:52:10
+ * Description: 日志统计测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	s := newTestViewer(t, searchFiles(), nil).defaultSource()
+
+	// 自动选择宽度：49 分钟的范围在 60 个桶以内取 1 分钟
+	stats, err := s.Stats(context.Background(), "", StatsOptions{Recursive: true, Attrs: []string{"request_id", "missing"}})
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.Files != 5 || stats.Total != 50 || stats.Interval != "1m0s" || len(stats.Buckets) != 50 || stats.Levels["INFO"] != 50 {
+		t.Fatalf("Unexpected stats: %+v", stats)
+	}
+	if b := stats.Buckets[3]; !b.Start.Equal(mustTime("2026-10-01T14:03:00Z")) || b.End.Sub(b.Start) != time.Minute || b.Total != 1 || b.Levels["INFO"] != 1 {
+		t.Errorf("Unexpected bucket: %+v", b)
+	}
+	if len(stats.Templates) != 1 || stats.Templates[0].Value != "step <*>" || stats.Templates[0].Count != 50 || stats.Templates[0].Example == "" {
+		t.Errorf("Unexpected templates: %+v", stats.Templates)
+	}
+	if want := []StatsCount{{Value: "r0", Count: 25}, {Value: "r1", Count: 25}}; !reflect.DeepEqual(stats.Attrs["request_id"], want) || len(stats.Attrs["missing"]) != 0 {
+		t.Errorf("Unexpected attrs: %+v", stats.Attrs)
+	}
+
+	// 指定宽度
+	stats, _ = s.Stats(context.Background(), "", StatsOptions{Recursive: true, Interval: 10 * time.Minute})
+	if len(stats.Buckets) != 5 || stats.Buckets[4].Total != 10 {
+		t.Errorf("Unexpected buckets: %+v", stats.Buckets)
+	}
+
+	// 过滤条件的时间范围完整时覆盖整个范围，包含没有日志的桶
+	filter := &Filter{From: mustTime("2026-10-01T14:10:00Z"), To: mustTime("2026-10-01T15:10:00Z")}
+	stats, _ = s.Stats(context.Background(), "", StatsOptions{Recursive: true, Filter: filter})
+	if stats.Total != 40 || stats.Interval != "5m0s" || len(stats.Buckets) != 12 || stats.Buckets[11].Total != 0 ||
+		!stats.Buckets[11].End.Equal(filter.To) {
+		t.Errorf("Unexpected stats for range: %+v", stats)
+	}
+
+	// 单个文件
+	stats, _ = s.Stats(context.Background(), "file-1.log", StatsOptions{Buckets: 5})
+	if stats.Files != 1 || stats.Total != 10 || stats.Interval != "10m0s" || len(stats.Buckets) != 5 {
+		t.Errorf("Unexpected file stats: %+v", stats)
+	}
+	if _, err := s.Stats(context.Background(), "nothing.log", StatsOptions{}); err == nil {
+		t.Error("Expected error for missing file")
+	}
+
+	// 指定宽度过小
+	filter = &Filter{From: mustTime("2026-01-01T00:00:00Z"), To: mustTime("2026-10-01T00:00:00Z")}
+	if _, err := s.Stats(context.Background(), "", StatsOptions{Filter: filter, Interval: time.Second}); !errors.Is(err, ErrTooManyBuckets) {
+		t.Errorf("Expected ErrTooManyBuckets, got %v", err)
+	}
+}
+
+func TestStats_Levels(t *testing.T) {
+	logDir := t.TempDir()
+	path := filepath.Join(logDir, "app.log")
+	writeIndexLines(t, path, 0, 200)
+	appendLine(t, path, `{"level":"WARN","msg":"no time"}`+"\n")
+
+	stats, err := New(&Config{LogDir: logDir}).defaultSource().Stats(context.Background(), "app.log", StatsOptions{Top: 1})
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	errorsInBuckets := 0
+	for _, b := range stats.Buckets {
+		errorsInBuckets += b.Levels["ERROR"]
+	}
+	// 200 分钟的范围取 5 分钟宽度
+	if stats.Interval != "5m0s" || len(stats.Buckets) != 40 || errorsInBuckets != 20 || stats.Untimed != 1 || stats.Levels["WARN"] != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if len(stats.Templates) != 1 || stats.Templates[0].Value != "order <*> 已处理 step-<*>" {
+		t.Errorf("Unexpected templates: %+v", stats.Templates)
+	}
+}
+
+func TestMessageTemplate(t *testing.T) {
+	tests := map[string]string{
+		"order 42 failed after 1.5s":                        "order <*> failed after <*>.<*>",
+		`user "alice" logged in from 10.0.0.1`:              "user <*> logged in from <*>.<*>.<*>.<*>",
+		"request 550e8400-e29b-41d4-a716-446655440000 done": "request <*> done",
+		"pointer 0xc000123abc deadbeef v2":                  "pointer <*> deadbeef v2",
+		"no variables here":                                 "no variables here",
+	}
+	for msg, want := range tests {
+		if got := messageTemplate(msg); got != want {
+			t.Errorf("%q: expected %q, got %q", msg, want, got)
+		}
+	}
+}
+
+func TestCounter_Bounded(t *testing.T) {
+	var c counter
+	for i := 0; i < 3; i++ {
+		c.add("frequent", "")
+	}
+	for i := 0; i < maxStatsDistinct*2; i++ {
+		c.add(fmt.Sprintf("v%d", i), "")
+	}
+	if len(c.counts) > maxStatsDistinct {
+		t.Errorf("Expected at most %d items, got %d", maxStatsDistinct, len(c.counts))
+	}
+	if top := c.top(1); top[0].Value != "frequent" || top[0].Count != 3 {
+		t.Errorf("Unexpected top item: %+v", top)
+	}
+}
+
+func TestStatsHandler(t *testing.T) {
+	handler := newTestViewer(t, searchFiles(), nil).Handler("/log")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/log/stats?name=file-0.log&keys=request_id&top=1&interval=5m&attr.request_id=r0", nil))
+	var resp struct {
+		Code int      `json:"code"`
+		Data LogStats `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Code != 200 {
+		t.Fatalf("Unexpected response: %d %s", w.Code, w.Body.String())
+	}
+	if resp.Data.Total != 5 || resp.Data.Interval != "5m0s" || len(resp.Data.Attrs["request_id"]) != 1 || len(resp.Data.Buckets) != 9 {
+		t.Errorf("Unexpected stats: %s", w.Body.String())
+	}
+
+	for _, query := range []string{"interval=10ms", "interval=soon", "top=0", "buckets=x", "q=level%3E%3D", "interval=1s&from=2020-01-01&to=2026-01-01"} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/log/stats?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d %s", query, w.Code, w.Body.String())
+		}
+	}
+}
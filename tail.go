@@ -0,0 +1,200 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 11:30:47
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 11:30:47
+ * Description: 实时跟踪日志文件
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	defaultTailInterval = 500 * time.Millisecond
+	maxTailLine         = 1 << 20 // 未写完的行超过该长度时截断发送，其余部分丢弃
+)
+
+// 跟踪事件类型
+const (
+	TailEntry    = "entry"    // 新日志
+	TailTruncate = "truncate" // 文件被截断（如 ClearFileContent）
+	TailRotate   = "rotate"   // 文件被轮转（重命名后重新创建）
+	TailEOF      = "eof"      // 压缩文件已全部发送，不会再有新内容
+)
+
+// TailEvent 跟踪事件
+type TailEvent struct {
+	Type  string    `json:"type"`
+	Entry *LogEntry `json:"entry,omitempty"`
+}
+
+// Tail 从文件末尾开始跟踪新写入的日志，直到 ctx 取消或 fn 返回错误
+func (s *Source) Tail(ctx context.Context, filename string, filter *Filter, fn func(TailEvent) error) error {
+	if s.memory != nil {
+		return s.tailMemory(ctx, filename, filter, fn)
+	}
+	opened, _, err := s.open(PermTail, filename)
+	if err != nil {
+		return err
+	}
+	if opened.compression != "" {
+		return s.tailCompressed(opened, filename, filter, fn)
+	}
+
+	file := opened.logHandle.(*os.File)
+	defer func() { file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	offset := info.Size()
+
+	interval := s.lv.config.TailInterval
+	if interval <= 0 {
+		interval = defaultTailInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// 文件为空时等到有内容后再识别格式
+	sample := readSample(file)
+	parser := s.lv.parser(filename, sample)
+	detected := len(sample) > 0
+
+	var (
+		pending  []byte
+		skipping bool // 丢弃超长行剩余的部分，直到下一个换行符
+	)
+	buf := make([]byte, 32*1024)
+	// drain 读取新增内容，只处理完整的行
+	drain := func() error {
+		for {
+			n, err := file.ReadAt(buf, offset)
+			if n > 0 {
+				offset += int64(n)
+				pending = append(pending, buf[:n]...)
+				if skipping {
+					if i := bytes.IndexByte(pending, '\n'); i >= 0 {
+						pending, skipping = pending[i+1:], false
+					} else {
+						pending = pending[:0]
+					}
+				}
+				if !detected && bytes.IndexByte(pending, '\n') >= 0 {
+					parser, detected = s.lv.parser(filename, pending), true
+				}
+				var emitErr error
+				if pending, emitErr = emitLines(pending, parser, filter, fn); emitErr != nil {
+					return emitErr
+				}
+				if len(pending) > maxTailLine {
+					// 截断的行无法按格式解析，作为原始文本发送
+					if entry, ok := (PlainParser{}).Parse(pending[:maxTailLine]); ok && filter.Match(&entry) {
+						if err := fn(TailEvent{Type: TailEntry, Entry: &entry}); err != nil {
+							return err
+						}
+					}
+					pending, skipping = nil, true
+				}
+			}
+			if errors.Is(err, io.EOF) || n == 0 {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for {
+		if err := drain(); err != nil {
+			return err
+		}
+
+		if info, err = file.Stat(); err != nil {
+			return err
+		}
+		if info.Size() < offset {
+			offset, pending, skipping, detected = 0, nil, false, false
+			if err := fn(TailEvent{Type: TailTruncate}); err != nil {
+				return err
+			}
+			continue
+		}
+		if current, err := s.statFile(PermTail, filename); err == nil && !os.SameFile(info, current) {
+			// 读完旧文件剩余内容后切换到新文件
+			next, _, err := s.openFile(PermTail, filename, os.O_RDONLY)
+			if err == nil {
+				if err := drain(); err != nil {
+					next.Close()
+					return err
+				}
+				file.Close()
+				file, offset, pending, skipping, detected = next, 0, nil, false, false
+				if err := fn(TailEvent{Type: TailRotate}); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// tailCompressed 压缩文件不会再增长，发送解压后的全部日志后结束
+func (s *Source) tailCompressed(file *logFile, filename string, filter *Filter, fn func(TailEvent) error) error {
+	defer file.Close()
+
+	parser := s.lv.fileParser(filename, file)
+	r, err := file.reader(0)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var emitErr error
+	err = readLines(r, 0, func(line []byte, _, _ int64) bool {
+		if entry, ok := parser.Parse(line); ok && filter.Match(&entry) {
+			emitErr = fn(TailEvent{Type: TailEntry, Entry: &entry})
+		}
+		return emitErr == nil
+	})
+	if err != nil {
+		return err
+	}
+	if emitErr != nil {
+		return emitErr
+	}
+	return fn(TailEvent{Type: TailEOF})
+}
+
+// emitLines 解析 data 中的完整行并发送，返回末尾未完成的部分
+func emitLines(data []byte, parser LineParser, filter *Filter, fn func(TailEvent) error) ([]byte, error) {
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			return data, nil
+		}
+		line := bytes.TrimRight(data[:i], "\r")
+		data = data[i+1:]
+		if entry, ok := parser.Parse(line); ok && filter.Match(&entry) {
+			if err := fn(TailEvent{Type: TailEntry, Entry: &entry}); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
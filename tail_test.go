@@ -0,0 +1,216 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 12:02:19
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 12:02:19
+ * Description: 实时跟踪测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func appendLine(t *testing.T, path, line string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open test file: %v", err)
+	}
+	defer file.Close()
+	file.WriteString(line)
+}
+
+// nextEvent 等待下一个跟踪事件
+func nextEvent(t *testing.T, events <-chan TailEvent) TailEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for tail event")
+		return TailEvent{}
+	}
+}
+
+func TestTail(t *testing.T) {
+	tempDir := t.TempDir()
+	lv := New(&Config{LogDir: tempDir, TailInterval: 10 * time.Millisecond})
+	path := filepath.Join(tempDir, "app.log")
+	appendLine(t, path, `{"level":"INFO","msg":"old"}`+"\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan TailEvent, 16)
+	done := make(chan error, 1)
+	filter := &Filter{Contains: "keep"}
+	go func() {
+		done <- lv.Tail(ctx, "app.log", filter, func(event TailEvent) error {
+			events <- event
+			return nil
+		})
+	}()
+	time.Sleep(30 * time.Millisecond)
+
+	// 新增内容，行未写完前不发送
+	appendLine(t, path, `{"level":"INFO","msg":"drop me"}`+"\n"+`{"level":"INFO","msg":"keep `)
+	time.Sleep(30 * time.Millisecond)
+	appendLine(t, path, `1"}`+"\n")
+	if event := nextEvent(t, events); event.Type != TailEntry || event.Entry.Msg != "keep 1" {
+		t.Fatalf("Expected entry keep 1, got %+v", event)
+	}
+
+	// 截断
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatalf("Failed to truncate: %v", err)
+	}
+	if event := nextEvent(t, events); event.Type != TailTruncate {
+		t.Fatalf("Expected truncate event, got %+v", event)
+	}
+	appendLine(t, path, `{"level":"INFO","msg":"keep 2"}`+"\n")
+	if event := nextEvent(t, events); event.Entry == nil || event.Entry.Msg != "keep 2" {
+		t.Fatalf("Expected entry keep 2, got %+v", event)
+	}
+
+	// 轮转：重命名后重新创建
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatalf("Failed to rotate: %v", err)
+	}
+	appendLine(t, path+".1", `{"level":"INFO","msg":"keep 3"}`+"\n")
+	appendLine(t, path, `{"level":"INFO","msg":"keep 4"}`+"\n")
+	var got []string
+	for len(got) < 3 {
+		event := nextEvent(t, events)
+		if event.Entry != nil {
+			got = append(got, event.Entry.Msg)
+		} else {
+			got = append(got, event.Type)
+		}
+	}
+	if strings.Join(got, ",") != "keep 3,rotate,keep 4" {
+		t.Errorf("Unexpected events after rotation: %v", got)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Tail returned error: %v", err)
+	}
+}
+
+func TestTail_LongLine(t *testing.T) {
+	tempDir := t.TempDir()
+	lv := New(&Config{LogDir: tempDir, TailInterval: 10 * time.Millisecond})
+	path := filepath.Join(tempDir, "app.log")
+	appendLine(t, path, `{"level":"INFO","msg":"old"}`+"\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan TailEvent, 16)
+	go lv.Tail(ctx, "app.log", nil, func(event TailEvent) error {
+		events <- event
+		return nil
+	})
+	time.Sleep(30 * time.Millisecond)
+
+	// 没有换行符的超长行截断后作为原始文本发送，剩余部分丢弃到下一个换行符
+	appendLine(t, path, `{"level":"INFO","msg":"`+strings.Repeat("x", maxTailLine))
+	event := nextEvent(t, events)
+	if event.Type != TailEntry || len(event.Entry.Msg) != maxTailLine || !strings.HasPrefix(event.Entry.Msg, `{"level"`) {
+		t.Fatalf("Expected truncated entry, got %s %d", event.Type, len(event.Entry.Msg))
+	}
+	appendLine(t, path, strings.Repeat("y", 1000)+`"}`+"\n"+`{"level":"INFO","msg":"next"}`+"\n")
+	if event := nextEvent(t, events); event.Entry == nil || event.Entry.Msg != "next" {
+		t.Fatalf("Expected entry next, got %+v", event)
+	}
+}
+
+func TestTailHandler(t *testing.T) {
+	tempDir := t.TempDir()
+	lv := New(&Config{LogDir: tempDir, TailInterval: 10 * time.Millisecond})
+	path := filepath.Join(tempDir, "app.log")
+	appendLine(t, path, "")
+
+	ts := httptest.NewServer(http.HandlerFunc(lv.TailHandler))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/log/tail?name=app.log&level=WARN")
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %s", ct)
+	}
+
+	appendLine(t, path, `{"level":"INFO","msg":"info"}`+"\n"+`{"level":"ERROR","msg":"boom"}`+"\n")
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	var event string
+	for {
+		select {
+		case line := <-lines:
+			if strings.HasPrefix(line, "event: ") {
+				event = strings.TrimPrefix(line, "event: ")
+			}
+			if strings.HasPrefix(line, "data: ") {
+				if event != TailEntry || !strings.Contains(line, `"msg":"boom"`) {
+					t.Fatalf("Unexpected event %s: %s", event, line)
+				}
+				return
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for SSE event")
+		}
+	}
+}
+
+func TestTailHandler_Head(t *testing.T) {
+	tempDir := t.TempDir()
+	lv := New(&Config{LogDir: tempDir})
+	appendLine(t, filepath.Join(tempDir, "app.log"), `{"level":"INFO","msg":"info"}`+"\n")
+
+	// HEAD 只返回响应头，不开始跟踪
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lv.TailHandler(w, httptest.NewRequest("HEAD", "/log/tail?name=app.log", nil))
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected HEAD to return without following the file")
+	}
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" || w.Body.Len() != 0 {
+		t.Errorf("Unexpected response: %d %v %q", w.Code, w.Header(), w.Body.String())
+	}
+}
+
+func TestTailHandler_MissingFile(t *testing.T) {
+	lv := New(&Config{LogDir: t.TempDir()})
+
+	req := httptest.NewRequest("GET", "/log/tail?name=missing.log", nil)
+	w := httptest.NewRecorder()
+	lv.TailHandler(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status InternalServerError, got %d", w.Code)
+	}
+}
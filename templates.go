@@ -0,0 +1,30 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 18:55:31
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 18:55:31
+ * Description: 内嵌页面模板及静态资源
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"embed"
+	"html/template"
+	"io/fs"
+)
+
+//go:embed templates/log.html templates/source
+var templateFS embed.FS
+
+// pageTemplate 日志查看页面模板
+var pageTemplate = template.Must(template.ParseFS(templateFS, "templates/log.html"))
+
+// Assets 返回页面使用的静态资源（css/js）
+func Assets() fs.FS {
+	assets, err := fs.Sub(templateFS, "templates/source")
+	if err != nil {
+		panic(err)
+	}
+	return assets
+}
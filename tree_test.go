@@ -0,0 +1,135 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/17 16:40:52
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/17 16:40:52
+ * Description: 目录树测试
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package goslogviewer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// treeFiles logs/<service>/<date>.log 结构的测试文件
+var treeFiles = infoFiles(
+	"root.log",
+	"api/2026-10-01.log",
+	"api/2026-10-02.log",
+	"worker/2026-10-01.log",
+	"worker/archive/2026-09-30.log",
+)
+
+func TestListLogFiles_Recursive(t *testing.T) {
+	lv := newTestViewer(t, treeFiles, nil)
+
+	tests := []struct {
+		opts ListOptions
+		want string
+	}{
+		{ListOptions{}, "root.log"},
+		{ListOptions{Recursive: true}, "api/2026-10-01.log,api/2026-10-02.log,root.log,worker/2026-10-01.log,worker/archive/2026-09-30.log"},
+		{ListOptions{Recursive: true, MaxDepth: 1}, "api/2026-10-01.log,api/2026-10-02.log,root.log,worker/2026-10-01.log"},
+	}
+	for _, tt := range tests {
+		files, err := lv.ListLogFiles(tt.opts)
+		if err != nil {
+			t.Fatalf("ListLogFiles failed: %v", err)
+		}
+		var names []string
+		for _, f := range files {
+			names = append(names, f.Name)
+		}
+		if got := strings.Join(names, ","); got != tt.want {
+			t.Errorf("ListLogFiles(%+v) = %s, want %s", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestGetLogTree(t *testing.T) {
+	lv := newTestViewer(t, treeFiles, nil)
+
+	tree, err := lv.GetLogTree(ListOptions{Desc: true})
+	if err != nil {
+		t.Fatalf("GetLogTree failed: %v", err)
+	}
+
+	var names []string
+	for _, child := range tree.Children {
+		names = append(names, child.Name)
+	}
+	if strings.Join(names, ",") != "api,worker,root.log" {
+		t.Fatalf("Unexpected top level: %v", names)
+	}
+	api := tree.Children[0]
+	if !api.Dir || api.Path != "api" || len(api.Children) != 2 || api.Children[0].Path != "api/2026-10-02.log" {
+		t.Errorf("Unexpected api node: %+v", api)
+	}
+	archive := tree.Children[1].Children[0]
+	if archive.Path != "worker/archive" || archive.Children[0].File == nil || archive.Children[0].File.Name != "worker/archive/2026-09-30.log" {
+		t.Errorf("Unexpected archive node: %+v", archive)
+	}
+}
+
+func TestSubpathAccess(t *testing.T) {
+	lv := newTestViewer(t, treeFiles, &Config{DevMode: true, EnableClear: true, EnableExport: true})
+
+	entries, err := lv.GetLogContent("worker/archive/2026-09-30.log")
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("GetLogContent on subpath failed: %v %v", entries, err)
+	}
+	if _, err := lv.GetLogContent("api/../root.log"); err != nil {
+		t.Errorf("Expected cleaned path inside LogDir to be allowed: %v", err)
+	}
+
+	for _, name := range []string{"../secret.log", "api/../../secret.log", "/etc/passwd", "api\\..\\..\\secret.log", "."} {
+		if _, err := lv.GetLogContent(name); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("GetLogContent(%q) = %v, want ErrInvalidPath", name, err)
+		}
+		if err := lv.ClearFileContent(name); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("ClearFileContent(%q) = %v, want ErrInvalidPath", name, err)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/log/exportFile?name=api/2026-10-01.log", nil)
+	w := httptest.NewRecorder()
+	lv.ExportFileHandler(w, req)
+	var response struct {
+		Code int    `json:"code"`
+		Data string `json:"data"`
+	}
+	json.NewDecoder(w.Body).Decode(&response)
+	if response.Code != 200 || !strings.Contains(response.Data, "api/2026-10-01.log") {
+		t.Errorf("Expected export of subpath, got %+v", response)
+	}
+
+	req = httptest.NewRequest("GET", "/log/getFileContent?name=../secret.log", nil)
+	w = httptest.NewRecorder()
+	lv.GetContentHandler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status BadRequest, got %d", w.Code)
+	}
+}
+
+func TestDeleteAllLogs_Recursive(t *testing.T) {
+	lv := newTestViewer(t, treeFiles, &Config{DevMode: true, EnableDelete: true})
+
+	if err := lv.DeleteAllLogs(); err != nil {
+		t.Fatalf("DeleteAllLogs failed: %v", err)
+	}
+	files, _ := lv.ListLogFiles(ListOptions{Recursive: true})
+	if len(files) != 0 {
+		t.Errorf("Expected no files left, got %v", files)
+	}
+	if _, err := os.Stat(filepath.Join(lv.config.LogDir, "worker", "archive")); err != nil {
+		t.Errorf("Expected directories to be kept: %v", err)
+	}
+}